@@ -13,6 +13,7 @@ import (
 	"strconv"
 
 	p9client "github.com/fhs/9fans-go/plan9/client"
+	"github.com/tw4452852/acme-lsp/internal/acmeutil"
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/tw4452852/acme-lsp/internal/lsp"
 	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp"
@@ -36,58 +37,175 @@ If L is run outside of acme (therefore $winid is not set), L will
 attempt to find the focused window ID by connecting to acmefocused
 (https://godoc.org/github.com/tw4452852/acme-lsp/cmd/acmefocused).
 
-	Usage: L <sub-command> [args...]
+Locations are sent to the plumber, or opened using acme's file
+server if the plumber isn't running. The LocationOpener option
+in the configuration file can be used to always use one or the other.
+
+	Usage: L [-f path:line:col] <sub-command> [args...]
 
 List of sub-commands:
 
-	comp [-e]
+	comp [-e|-w]
 		Print candidate completions at the cursor position. If
 		-e (edit) flag is given and there is only one candidate,
-		the completion is applied instead of being printed.
+		the completion is applied instead of being printed. If
+		-w (window) flag is given, the candidates are listed in
+		the /LSP/Completion window instead, where executing
+		(middle-clicking) a candidate applies it. Candidates are
+		filtered by the text being completed as configured by the
+		CompletionMatcher option, and ordered by the server's ranking.
+		If the window is edited while the candidates are listed,
+		completion is requested again when a candidate is executed,
+		and the candidate with the same label is applied.
 
 	def [-p]
 		Find where the symbol at the cursor position is defined
-		and send the location to the plumber. If -p flag is given,
-		the location is printed to stdout instead.
+		and open the location in acme. If -p flag is given, the
+		location is printed to stdout instead.
+
+	exec [command [args]]
+		List the commands (e.g. "gopls.tidy") that can be executed
+		by the LSP server for the current file. If command is given,
+		it's executed instead. The optional args is a JSON array of
+		the command arguments, which may contain the same
+		placeholders as raw (e.g. '[{"URIs": [$uri]}]'). Edits made
+		by the command are applied to acme windows. Messages sent
+		by the server are shown in the /LSP/Messages window if the
+		MessageWindow option is set in the configuration file.
+		Since the edits are applied by acme-lsp, exec can't be used
+		with the -f flag.
 
 	fmt
 		Organize imports and format current window buffer.
 
-	hov
+	hov [-w]
 		Show more information about the symbol under the cursor
 		("hover").
 
-	impls
+	impls [-w]
 		List implementation location(s) of the symbol under the cursor.
 
-	refs
+	lens [n]
+		List code lenses (e.g. "run test") in the current file,
+		numbered from 0. If n is given, the nth code lens is
+		resolved and its command is executed.
+
+	links
+		List links (e.g. import paths, URLs in comments) in the
+		current file. Each link is followed by its target, which
+		is a file location or URL that can be plumbed. A link whose
+		target can't be resolved is listed as unresolved.
+
+	next
+		Select the next tab stop (placeholder) of the snippet last
+		inserted by completion. The first tab stop is selected when
+		the snippet is inserted.
+
+	refs [-w]
 		List locations where the symbol under the cursor is used
 		("references").
 
+	raw [-n] <method> [params]
+		Send the LSP request with the given method (e.g.
+		"gopls/gcDetails") and JSON parameters to the LSP server
+		for the current file, and print the JSON result. If -n
+		flag is given, a notification is sent instead. In params,
+		$uri, $textDocument, $position, and $selection are replaced
+		with the document URI, document identifier, cursor position,
+		and selected range, except inside JSON strings. The default
+		params are {"textDocument": $textDocument, "position": $position}.
+
+	restart [serverkey|filename]
+		Shut down the LSP server for the current file, start it
+		again with the current workspace folders, and reopen the
+		documents that were open in it. Use this when the server
+		stops responding. The server can also be given by its key in
+		the configuration file (e.g. gopls), which restarts all the
+		servers with that key, or by the name of a file it handles.
+		A server given by its address is only disconnected from and
+		dialed again, since it may be used by other clients.
+
 	rn <newname>
 		Rename the symbol under the cursor to newname.
 
 	sig
 		Show signature help for the function, method, etc. under
-		the cursor.
-
-	syms
+		the cursor. The active signature is shown first, with the
+		parameter under the cursor surrounded by «».
+
+	status [-w]
+		Show the status of the LSP servers: the command or address,
+		process ID, uptime, number of restarts and the error of the
+		last exit, supported features, and the open documents with
+		their versions. If -w flag is given, the status is shown in
+		the /LSP/Status window, where executing Reload refreshes it.
+
+	stop [serverkey|filename]
+		Shut down the LSP server, which is given as in restart. The
+		server is started again when it's needed, and the documents
+		that were open in it are reopened.
+
+	syms [-w]
 		List symbols in the current file.
 
+	where
+		Print the path of symbols (e.g. "Type › Method") enclosing
+		the cursor, followed by the location of each symbol.
+
 	type [-p]
 		Find where the type of the symbol at the cursor position
-		is defined and send the location to the plumber. If -p
-		flag is given, the location is printed to stdout instead.
+		is defined and open the location in acme. If -p flag is
+		given, the location is printed to stdout instead.
 
-	assist [comp|hov|sig]
+	assist [comp|hov|sig|hints]
 		A new window is created where completion (comp), hover
 		(hov), or signature help (sig) output is shown depending
-		on the cursor position in the focused window and the
-		text surrounding the cursor. If the optional argument is
-		given, the output will be limited to only that command.
+		on the cursor position in the focused window, the text
+		surrounding the cursor, and the trigger characters of the
+		language server. If the optional argument is given, the
+		output will be limited to only that command. The output
+		is updated shortly after the text is edited or another
+		window is focused, and it starts with the path of symbols
+		enclosing the cursor (see where). Acme reports no event
+		when the cursor is moved by clicking, so such a move is
+		picked up at the next edit or when the window is focused
+		again. To follow edits, the event file of the focused
+		window is read while the assist window is open, during
+		which acme hides the Undo, Redo, and Put commands of the
+		focused window's tag, and other programs reading its
+		events (e.g. win) won't get them all. The focused window
+		is released when another window is focused.
 		Note: this is a very experimental feature, and may not
 		be very useful in practice.
 
+	hints
+		Same as "assist hints". A new window is created where
+		inlay hints (e.g. parameter names, inferred types) for
+		the lines surrounding the cursor in the focused window
+		are shown as "line:col label" entries.
+
+	outline
+		A new window (/LSP/Outline) is created where the symbols
+		of the focused window are shown as a tree. The symbol
+		enclosing the cursor is marked with », and the outline
+		is refreshed as the file is edited or saved. Looking
+		(right-clicking) at a symbol jumps to it. Like assist,
+		the outline reads the event file of the focused window
+		while it's open.
+
+	back
+		Go back to the location opened before the current one in
+		the navigation history. Each time def or type opens a
+		location, the cursor position it was run from and the
+		destination are recorded in the history.
+
+	forward
+		Go forward in the navigation history, undoing back.
+
+	jumps
+		List the navigation history. The current entry is marked
+		with >.
+
 	ws
 		List current set of workspace directories.
 
@@ -98,8 +216,51 @@ List of sub-commands:
 	ws- [directories...]
 		Remove given directories to the set of workspace directories.
 		Current working directory is removed if no directory is specified.
+
+If the -f flag is given, the command is run at the given file address
+instead of the cursor position of an acme window, so that L can be used
+from shell scripts and other editors. The file is read from acme if it's
+open there, or from disk otherwise. Only def, hov, impls, lens, links,
+raw, refs, rn, sig, syms, type, and where are supported; def and type
+print the locations instead of opening them. Files changed by rn are
+written to disk unless they are open in acme, or printed as a diff if
+the -diff flag is given.
+
+If the -json flag is given, results (e.g. locations, symbols, hover,
+completion items) are printed as JSON instead of human-readable text.
+Commands that open locations (def, type, back, forward) print them
+instead, and rn prints the workspace edit instead of applying it.
+
+The output of hov, impls, refs, and syms is shown in an acme window
+named after the command (e.g. /LSP/refs) instead of being printed if
+the -w flag is given or the ResultWindows option is set in the
+configuration file, unless -json is given. The window is reused by
+later runs of the command, and executing Reload in its tag runs the
+command again at the original cursor position.
 `
 
+var (
+	jsonOutput = flag.Bool("json", false, "print results as JSON")
+	fileAddr   = flag.String("f", "", "run command at file address `path:line:col` instead of acme window")
+	diffOutput = flag.Bool("diff", false, "print edits to files as a diff instead of writing them (with -f)")
+)
+
+// headlessCommands are the sub-commands that can be run with -f.
+var headlessCommands = map[string]bool{
+	"def":   true,
+	"hov":   true,
+	"impls": true,
+	"lens":  true,
+	"links": true,
+	"raw":   true,
+	"refs":  true,
+	"rn":    true,
+	"sig":   true,
+	"syms":  true,
+	"type":  true,
+	"where": true,
+}
+
 func usage() {
 	os.Stderr.Write([]byte(mainDoc))
 	fmt.Fprintf(os.Stderr, "\n")
@@ -148,6 +309,9 @@ func run(cfg *config.Config, args []string) error {
 		if err != nil {
 			return err
 		}
+		if *jsonOutput {
+			return acmelsp.PrintJSON(os.Stdout, append([]protocol.WorkspaceFolder{}, folders...))
+		}
 		for _, d := range folders {
 			fmt.Printf("%v\n", d.Name)
 		}
@@ -176,21 +340,86 @@ func run(cfg *config.Config, args []string) error {
 		args = args[1:]
 		sm := &acmelsp.UnitServerMatcher{Server: server}
 		if len(args) == 0 {
-			return acmelsp.Assist(sm, "auto")
+			return acmelsp.Assist(sm, "auto", cfg)
 		}
 		switch args[0] {
-		case "comp", "sig", "hov", "auto":
-			return acmelsp.Assist(sm, args[0])
+		case "comp", "sig", "hov", "hints", "auto":
+			return acmelsp.Assist(sm, args[0], cfg)
 		}
 		return fmt.Errorf("unknown assist command %q", args[0])
+	case "hints":
+		sm := &acmelsp.UnitServerMatcher{Server: server}
+		return acmelsp.Assist(sm, "hints", cfg)
+	case "outline":
+		sm := &acmelsp.UnitServerMatcher{Server: server}
+		return acmelsp.Outline(sm, cfg)
+	case "back", "forward":
+		delta := -1
+		if args[0] == "forward" {
+			delta = 1
+		}
+		loc, err := server.Jump(ctx, &proxy.JumpParams{Delta: delta})
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return acmelsp.PrintJSON(os.Stdout, loc)
+		}
+		return acmelsp.OpenLocations([]protocol.Location{*loc}, cfg.LocationOpener)
+	case "status":
+		if len(args) > 1 && args[1] == "-w" {
+			return server.ShowResults(ctx, &proxy.ShowResultsParams{
+				Command: "status",
+			})
+		}
+		rc := acmelsp.NewRemoteCmd(server, 0)
+		rc.JSON = *jsonOutput
+		return rc.Status(ctx)
+	case "restart", "stop":
+		if len(args) > 2 {
+			usage()
+		}
+		name, err := serverName(args[1:])
+		if err != nil {
+			return err
+		}
+		params := &proxy.ServerParams{Name: name}
+		if args[0] == "stop" {
+			return server.StopServer(ctx, params)
+		}
+		return server.RestartServer(ctx, params)
+	case "jumps":
+		jumps, err := server.Jumps(ctx)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return acmelsp.PrintJSON(os.Stdout, jumps)
+		}
+		return acmelsp.PrintJumps(os.Stdout, jumps)
 	}
 
-	winid, err := getWinID()
-	if err != nil {
-		return err
+	var rc *acmelsp.RemoteCmd
+	if *fileAddr != "" {
+		if !headlessCommands[args[0]] {
+			return fmt.Errorf("command %q is not supported with -f", args[0])
+		}
+		rc, err = acmelsp.FileRemoteCmd(server, *fileAddr)
+		if err != nil {
+			return err
+		}
+		defer rc.Close(ctx)
+		rc.Diff = *diffOutput
+	} else {
+		winid, err := getWinID()
+		if err != nil {
+			return err
+		}
+		rc = acmelsp.NewRemoteCmd(server, winid)
 	}
-
-	rc := acmelsp.NewRemoteCmd(server, winid)
+	rc.JSON = *jsonOutput
+	rc.CompletionMatcher = cfg.CompletionMatcher
+	rc.LocationOpener = cfg.LocationOpener
 
 	// In case the window has unsaved changes (it's dirty), sync changes with LSP server.
 	err = rc.DidChange(ctx)
@@ -198,19 +427,68 @@ func run(cfg *config.Config, args []string) error {
 		return fmt.Errorf("DidChange failed: %v", err)
 	}
 
+	switch args[0] {
+	case "hov", "impls", "refs", "syms":
+		if !rc.JSON && *fileAddr == "" && (cfg.ResultWindows || len(args) > 1 && args[1] == "-w") {
+			return rc.ShowResults(ctx, args[0])
+		}
+	}
+
 	switch args[0] {
 	case "comp":
 		args = args[1:]
+		if len(args) > 0 && args[0] == "-w" {
+			return rc.CompletionWindow(ctx)
+		}
 		return rc.Completion(ctx, len(args) > 0 && args[0] == "-e")
 	case "def":
 		args = args[1:]
-		return rc.Definition(ctx, len(args) > 0 && args[0] == "-p")
+		return rc.Definition(ctx, *fileAddr != "" || len(args) > 0 && args[0] == "-p")
+	case "exec":
+		args = args[1:]
+		switch len(args) {
+		case 0:
+			return rc.Commands(ctx)
+		case 1:
+			return rc.ExecuteCommand(ctx, args[0], "[]")
+		case 2:
+			return rc.ExecuteCommand(ctx, args[0], args[1])
+		}
+		usage()
 	case "fmt":
 		return rc.OrganizeImportsAndFormat(ctx)
 	case "hov":
 		return rc.Hover(ctx)
 	case "impls":
 		return rc.Implementation(ctx, true)
+	case "lens":
+		args = args[1:]
+		if len(args) == 0 {
+			return rc.CodeLens(ctx, -1)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid code lens number %q", args[0])
+		}
+		return rc.CodeLens(ctx, n)
+	case "links":
+		return rc.DocumentLink(ctx)
+	case "next":
+		return rc.NextTabStop(ctx)
+	case "raw":
+		args = args[1:]
+		notify := len(args) > 0 && args[0] == "-n"
+		if notify {
+			args = args[1:]
+		}
+		if len(args) < 1 || len(args) > 2 {
+			usage()
+		}
+		params := acmelsp.DefaultRawParams
+		if len(args) > 1 {
+			params = args[1]
+		}
+		return rc.Raw(ctx, args[0], params, notify)
 	case "refs":
 		return rc.References(ctx)
 	case "rn":
@@ -223,9 +501,11 @@ func run(cfg *config.Config, args []string) error {
 		return rc.SignatureHelp(ctx)
 	case "syms":
 		return rc.DocumentSymbol(ctx)
+	case "where":
+		return rc.Where(ctx)
 	case "type":
 		args = args[1:]
-		return rc.TypeDefinition(ctx, len(args) > 0 && args[0] == "-p")
+		return rc.TypeDefinition(ctx, *fileAddr != "" || len(args) > 0 && args[0] == "-p")
 	}
 	return fmt.Errorf("unknown command %q", args[0])
 }
@@ -253,6 +533,29 @@ func dirsOrCurrentDir(dirs []string) ([]protocol.WorkspaceFolder, error) {
 	return lsp.DirsToWorkspaceFolders(dirs)
 }
 
+// serverName returns the server key or filename given in args, or the
+// name of the file in the focused window if args is empty. Filenames
+// are made absolute, since acme-lsp may run in another directory.
+func serverName(args []string) (string, error) {
+	if len(args) == 0 {
+		winid, err := getWinID()
+		if err != nil {
+			return "", err
+		}
+		w, err := acmeutil.OpenWin(winid)
+		if err != nil {
+			return "", err
+		}
+		defer w.CloseFiles()
+		return w.Filename()
+	}
+	name := args[0]
+	if _, err := os.Stat(name); err == nil || filepath.Base(name) != name {
+		return filepath.Abs(name)
+	}
+	return name, nil
+}
+
 func getFocusedWinID(addr string) (string, error) {
 	winid := os.Getenv("winid")
 	if winid == "" {
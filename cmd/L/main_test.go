@@ -66,3 +66,27 @@ func TestGetFocusedWinIDFromServer(t *testing.T) {
 		t.Errorf("$winid is %v; want %v", got, want)
 	}
 }
+
+func TestServerName(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	for _, tc := range []struct {
+		arg, name string
+	}{
+		{"gopls", "gopls"},
+		{"main.go", filepath.Join(cwd, "main.go")},
+		{"sub/x.go", filepath.Join(cwd, "sub/x.go")},
+		{"/home/gopher/x.py", "/home/gopher/x.py"},
+	} {
+		name, err := serverName([]string{tc.arg})
+		if err != nil {
+			t.Errorf("serverName(%q) failed: %v", tc.arg, err)
+			continue
+		}
+		if name != tc.name {
+			t.Errorf("serverName(%q) is %q; want %q", tc.arg, name, tc.name)
+		}
+	}
+}
@@ -119,7 +119,7 @@ func run(cfg *config.Config, args []string) error {
 		if len(args) >= 2 {
 			assist = args[1]
 		}
-		if err := acmelsp.Assist(serverSet, assist); err != nil {
+		if err := acmelsp.Assist(serverSet, assist, cfg); err != nil {
 			return fmt.Errorf("assist failed: %v", err)
 		}
 		return nil
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+)
+
+// runCheck implements the check sub-command and returns the exit status.
+func runCheck(ctx context.Context, cfg *config.Config, args []string) int {
+	f := flag.NewFlagSet("check", flag.ExitOnError)
+	format := f.String("format", acmelsp.PlainFormat, "output format: plain, json, sarif or errorformat")
+	severity := f.String("severity", "error", "exit with status 1 if any diagnostic is at least this severe")
+	quiet := f.Duration("quiet", 5*time.Second, "wait until no diagnostics are published for this long")
+	timeout := f.Duration("timeout", 5*time.Minute, "give up waiting for diagnostics after this long")
+	f.Parse(args)
+
+	sev, err := acmelsp.ParseSeverity(*severity)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	switch *format {
+	case acmelsp.PlainFormat, acmelsp.JSONFormat, acmelsp.SARIFFormat, acmelsp.ErrorformatFormat:
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if f.NArg() > 0 {
+		cfg.WorkspaceDirectories = f.Args()
+	} else {
+		cfg.WorkspaceDirectories = []string{"."}
+	}
+	cfg.HideDiagnostics = false
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	// Print the diagnostics collected so far even if the check failed.
+	diags, checkErr := acmelsp.Check(ctx, cfg, *quiet)
+	if diags == nil && checkErr != nil {
+		log.Fatalf("%v", checkErr)
+	}
+	if err := acmelsp.PrintDiagnostics(os.Stdout, *format, diags); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if checkErr != nil {
+		log.Printf("%v", checkErr)
+		return 2
+	}
+	if acmelsp.CountDiagnostics(diags, sev) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -29,7 +29,35 @@ Also, when Put is executed in an acme window, acme-lsp will organize
 import paths in the window and format it by default. This behavior can
 be changed by the FormatOnPut and CodeActionsOnPut configuration options.
 
+If the -lsp.addr flag (or LSPAddress configuration option) is given,
+acme-lsp also listens on that address for connections from other LSP
+clients, such as editors or scripts. It acts as a standard LSP server
+for them, forwarding each request to the LSP server handling the
+document, so that the clients share the running servers with acme.
+Diagnostics are sent to acme and to the clients with the document
+open. Edits requested by a server while it executes a command for a
+client are sent to that client instead of being applied in acme.
+
+Messages sent by the LSP servers to be shown to the user (e.g. while
+executing a command with L exec) are logged, or shown in a
+"/LSP/Messages" window if the MessageWindow configuration option is set.
+
+The check sub-command runs without acme, which is useful in continuous
+integration. It starts the LSP servers for the files within the given
+directories (or the current directory), opens every file handled by a
+server, and waits until the servers stop publishing diagnostics for the
+duration given by -quiet (progress notifications aren't used). The
+diagnostics are then printed in the format given by -format: plain
+(same as the "/LSP/Diagnostics" window), json, sarif, or errorformat
+(file:line:col: severity: message). Check exits with status 1 if any
+diagnostic is at least as severe as -severity (error, warning,
+information, or hint). Files which can't be read, or whose server can't
+be started, are reported and skipped. Check then prints the diagnostics
+of the other files and exits with status 2, as it does if -timeout
+expires before the servers are quiet.
+
 	Usage: acme-lsp [flags]
+	       acme-lsp [flags] check [-format f] [-severity s] [-quiet d] [-timeout d] [dir ...]
 
   -acme.addr string
     	address where acme is serving 9P file system (default "/tmp/ns.fhs.:0/acme")
@@ -39,6 +67,10 @@ be changed by the FormatOnPut and CodeActionsOnPut configuration options.
     	turn on debugging prints (deprecated: use -v)
   -dial value
     	language server address for filename match (e.g. '\.go$:localhost:4389')
+  -lsp.addr string
+    	address where acme-lsp listens for LSP clients (disabled if empty)
+  -lsp.net string
+    	network where acme-lsp listens for LSP clients (default "unix")
   -proxy.addr string
     	address used for communication between acme-lsp and L (default "/tmp/ns.fhs.:0/acme-lsp.rpc")
   -proxy.net string
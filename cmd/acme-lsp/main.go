@@ -44,7 +44,35 @@ Also, when Put is executed in an acme window, acme-lsp will organize
 import paths in the window and format it by default. This behavior can
 be changed by the FormatOnPut and CodeActionsOnPut configuration options.
 
+If the -lsp.addr flag (or LSPAddress configuration option) is given,
+acme-lsp also listens on that address for connections from other LSP
+clients, such as editors or scripts. It acts as a standard LSP server
+for them, forwarding each request to the LSP server handling the
+document, so that the clients share the running servers with acme.
+Diagnostics are sent to acme and to the clients with the document
+open. Edits requested by a server while it executes a command for a
+client are sent to that client instead of being applied in acme.
+
+Messages sent by the LSP servers to be shown to the user (e.g. while
+executing a command with L exec) are logged, or shown in a
+"/LSP/Messages" window if the MessageWindow configuration option is set.
+
+The check sub-command runs without acme, which is useful in continuous
+integration. It starts the LSP servers for the files within the given
+directories (or the current directory), opens every file handled by a
+server, and waits until the servers stop publishing diagnostics for the
+duration given by -quiet (progress notifications aren't used). The
+diagnostics are then printed in the format given by -format: plain
+(same as the "/LSP/Diagnostics" window), json, sarif, or errorformat
+(file:line:col: severity: message). Check exits with status 1 if any
+diagnostic is at least as severe as -severity (error, warning,
+information, or hint). Files which can't be read, or whose server can't
+be started, are reported and skipped. Check then prints the diagnostics
+of the other files and exits with status 2, as it does if -timeout
+expires before the servers are quiet.
+
 	Usage: acme-lsp [flags]
+	       acme-lsp [flags] check [-format f] [-severity s] [-quiet d] [-timeout d] [dir ...]
 `
 
 func usage() {
@@ -59,6 +87,9 @@ func main() {
 	cfg := cmd.Setup(config.LangServerFlags | config.ProxyFlags)
 
 	ctx := context.Background()
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck(ctx, cfg, flag.Args()[1:]))
+	}
 	app, err := NewApplication(ctx, cfg, flag.Args())
 	if err != nil {
 		log.Fatalf("%v", err)
@@ -73,13 +104,18 @@ type Application struct {
 	cfg *config.Config
 	fm  *acmelsp.FileManager
 	ss  *acmelsp.ServerSet
+	lc  *acmelsp.LSPClientSet
 }
 
 func NewApplication(ctx context.Context, cfg *config.Config, args []string) (*Application, error) {
-	ss, err := acmelsp.NewServerSet(cfg, acmelsp.NewDiagnosticsWriter())
+	lc := acmelsp.NewLSPClientSet(acmelsp.NewDiagnosticsWriter())
+	ss, err := acmelsp.NewServerSet(cfg, lc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create server set: %v", err)
 	}
+	if cfg.MessageWindow {
+		ss.MessageWriter = acmelsp.NewMessageWriter()
+	}
 
 	if len(ss.Data) == 0 {
 		return nil, fmt.Errorf("no servers found in the configuration file or command line flags")
@@ -89,16 +125,27 @@ func NewApplication(ctx context.Context, cfg *config.Config, args []string) (*Ap
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file manager: %v", err)
 	}
+	fm.Clients = lc
 	return &Application{
 		cfg: cfg,
 		ss:  ss,
 		fm:  fm,
+		lc:  lc,
 	}, nil
 }
 
 func (app *Application) Run(ctx context.Context) error {
 	go app.fm.Run()
 
+	if app.cfg.LSPAddress != "" {
+		go func() {
+			err := acmelsp.ListenAndServeLSP(ctx, app.cfg, app.ss, app.fm, app.lc)
+			if err != nil {
+				log.Printf("LSP listener failed: %v", err)
+			}
+		}()
+	}
+
 	err := acmelsp.ListenAndServeProxy(ctx, app.cfg, app.ss, app.fm)
 	if err != nil {
 		return fmt.Errorf("proxy failed: %v", err)
@@ -44,6 +44,8 @@ type Win struct {
 	buf        []byte
 	e2, e3, e4 Event
 	name       string
+	fsys       *client.Fsys // if non-nil, used instead of the shared connection
+	hangup     func() error // hangs up the window's own connection, if any
 
 	errorPrefix string
 }
@@ -227,6 +229,33 @@ func Open(id int, ctl *client.Fid) (*Win, error) {
 	return w, nil
 }
 
+// OpenConn is like Open, but the window's files are opened over a new
+// connection to acme instead of the connection shared by the process.
+// The connection is hung up by Hangup or CloseFiles. The window isn't
+// managed by this package (e.g. found by Show).
+func OpenConn(id int) (*Win, error) {
+	fs, hangup, err := dialAcme()
+	if err != nil {
+		return nil, err
+	}
+	return &Win{
+		id:     id,
+		fsys:   fs,
+		hangup: hangup,
+	}, nil
+}
+
+// Hangup hangs up the connection to acme of a window opened with
+// OpenConn, which makes acme close the window's files, and interrupts
+// a ReadEvent waiting for an event. Unlike other methods, it may be
+// called while another goroutine is using the window.
+func (w *Win) Hangup() error {
+	if w.hangup == nil {
+		return errors.New("window doesn't have its own connection to acme")
+	}
+	return w.hangup()
+}
+
 // Addr writes format, ... to the window's addr file.
 func (w *Win) Addr(format string, args ...interface{}) error {
 	return w.Fprintf("addr", format, args...)
@@ -235,6 +264,9 @@ func (w *Win) Addr(format string, args ...interface{}) error {
 // CloseFiles closes all the open files associated with the window w.
 // (These file descriptors are cached across calls to Ctl, etc.)
 func (w *Win) CloseFiles() {
+	if w.hangup != nil {
+		w.hangup()
+	}
 	w.ctl.Close()
 	w.ctl = nil
 
@@ -312,8 +344,12 @@ func (w *Win) fid(name string) (*client.Fid, error) {
 		return nil, errors.New("unknown acme file: " + name)
 	}
 	if *f == nil {
+		fs := fsys
+		if w.fsys != nil {
+			fs = w.fsys
+		}
 		var err error
-		*f, err = fsys.Open(fmt.Sprintf("%d/%s", w.id, name), mode)
+		*f, err = fs.Open(fmt.Sprintf("%d/%s", w.id, name), mode)
 		if err != nil {
 			return nil, err
 		}
@@ -2,7 +2,12 @@
 
 package acme
 
-import "github.com/fhs/9fans-go/plan9/client"
+import (
+	"errors"
+	"os"
+
+	"github.com/fhs/9fans-go/plan9/client"
+)
 
 func mountAcme() {
 	if Network == "" || Address == "" {
@@ -10,3 +15,22 @@ func mountAcme() {
 	}
 	fsys, fsysErr = client.Mount(Network, Address)
 }
+
+// dialAcme returns a new connection to acme and a function which hangs
+// it up. The 9P server closes the files opened over the connection when
+// it's hung up.
+func dialAcme() (*client.Fsys, func() error, error) {
+	if Network == "" || Address == "" {
+		return nil, nil, errors.New("network or address not set")
+	}
+	c, err := client.Dial(Network, Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs, err := c.Attach(nil, os.Getenv("USER"), "")
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return fs, c.Close, nil
+}
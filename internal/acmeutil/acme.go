@@ -31,6 +31,16 @@ func OpenWin(id int) (*Win, error) {
 	return &Win{w}, nil
 }
 
+// OpenWinConn opens the window with the given id over
+// its own connection to acme (see acme.OpenConn).
+func OpenWinConn(id int) (*Win, error) {
+	w, err := acme.OpenConn(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Win{w}, nil
+}
+
 func OpenCurrentWin() (*Win, error) {
 	id, err := strconv.Atoi(os.Getenv("winid"))
 	if err != nil {
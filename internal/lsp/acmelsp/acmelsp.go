@@ -17,6 +17,7 @@ import (
 	"github.com/tw4452852/acme-lsp/internal/acme"
 	"github.com/tw4452852/acme-lsp/internal/acmeutil"
 	"github.com/tw4452852/acme-lsp/internal/lsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
 	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
 	"github.com/tw4452852/acme-lsp/internal/lsp/text"
@@ -55,7 +56,10 @@ func WindowRemoteCmd(ss *ServerSet, fm *FileManager, winid int) (*RemoteCmd, err
 		return nil, fmt.Errorf("DidChange failed: %v", err)
 	}
 
-	return NewRemoteCmd(srv.Client, winid), nil
+	rc := NewRemoteCmd(srv.Client, winid)
+	rc.CompletionMatcher = ss.cfg.CompletionMatcher
+	rc.LocationOpener = ss.cfg.LocationOpener
+	return rc, nil
 }
 
 func getLine(p string, l int) string {
@@ -98,6 +102,27 @@ func PrintLocations(w io.Writer, loc []protocol.Location) error {
 	return nil
 }
 
+// OpenLocations opens the locations in acme, either by sending them
+// to the plumber or by using acme's file server directly, as
+// determined by opener (see config.File.LocationOpener). The plumber
+// is preferred when opener is "Auto", but acme is used if it isn't
+// running.
+func OpenLocations(locations []protocol.Location, opener string) error {
+	switch opener {
+	case config.PlumberOpener:
+		return PlumbLocations(locations)
+	case config.AcmeOpener:
+		return showLocations(locations)
+	}
+	p, err := plumb.Open("send", plan9.OWRITE)
+	if err != nil {
+		dprintf("failed to open plumber (%v); opening locations in acme\n", err)
+		return showLocations(locations)
+	}
+	defer p.Close()
+	return sendLocations(p, locations)
+}
+
 // PlumbLocations sends the locations to the plumber.
 func PlumbLocations(locations []protocol.Location) error {
 	p, err := plumb.Open("send", plan9.OWRITE)
@@ -105,6 +130,10 @@ func PlumbLocations(locations []protocol.Location) error {
 		return fmt.Errorf("failed to open plumber: %v", err)
 	}
 	defer p.Close()
+	return sendLocations(p, locations)
+}
+
+func sendLocations(p io.Writer, locations []protocol.Location) error {
 	for _, loc := range locations {
 		err := plumbLocation(&loc).Send(p)
 		if err != nil {
@@ -115,12 +144,9 @@ func PlumbLocations(locations []protocol.Location) error {
 }
 
 func plumbLocation(loc *protocol.Location) *plumb.Message {
-	// LSP uses zero-based offsets.
-	// Place the cursor *before* the location range.
-	pos := loc.Range.Start
 	attr := &plumb.Attribute{
 		Name:  "addr",
-		Value: fmt.Sprintf("%v-#0+#%v", pos.Line+1, pos.Character),
+		Value: locationAddr(loc),
 	}
 	return &plumb.Message{
 		Src:  "acme-lsp",
@@ -132,6 +158,71 @@ func plumbLocation(loc *protocol.Location) *plumb.Message {
 	}
 }
 
+// locationAddr returns the acme address of the location.
+func locationAddr(loc *protocol.Location) string {
+	// LSP uses zero-based offsets.
+	// Place the cursor *before* the location range.
+	pos := loc.Range.Start
+	return fmt.Sprintf("%v-#0+#%v", pos.Line+1, pos.Character)
+}
+
+// showLocations opens the locations using acme's file server, the way
+// the plumber would: the file is loaded into a new window unless it's
+// already open, and dot is set to the location.
+func showLocations(locations []protocol.Location) error {
+	wins, err := acme.Windows()
+	if err != nil {
+		return fmt.Errorf("failed to read list of acme index: %v", err)
+	}
+	winid := make(map[string]int, len(wins))
+	for _, info := range wins {
+		winid[info.Name] = info.ID
+	}
+	for _, loc := range locations {
+		fname := text.ToPath(loc.URI)
+		id, err := showLocation(winid[fname], fname, &loc)
+		if err != nil {
+			return fmt.Errorf("failed to open %v in acme: %v", fname, err)
+		}
+		winid[fname] = id
+	}
+	return nil
+}
+
+// showLocation shows the location in the acme window with the given ID,
+// or in a new window if the ID is 0. It returns the ID of the window.
+func showLocation(id int, fname string, loc *protocol.Location) (int, error) {
+	var (
+		w   *acmeutil.Win
+		err error
+	)
+	if id > 0 {
+		w, err = acmeutil.OpenWin(id)
+	} else {
+		w, err = acmeutil.NewWin()
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer w.CloseFiles()
+
+	if id == 0 {
+		if err := w.Name("%v", fname); err != nil {
+			return 0, err
+		}
+		if err := w.Ctl("get"); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.Addr("%v", locationAddr(loc)); err != nil {
+		return 0, err
+	}
+	if err := w.Ctl("dot=addr"); err != nil {
+		return 0, err
+	}
+	return w.ID(), w.Ctl("show")
+}
+
 type FormatServer interface {
 	InitializeResult(context.Context, *protocol.TextDocumentIdentifier) (*protocol.InitializeResult, error)
 	DidChange(context.Context, *protocol.DidChangeTextDocumentParams) error
@@ -219,7 +310,9 @@ func CodeActionAndFormat(ctx context.Context, server FormatServer, doc *protocol
 	return nil
 }
 
-func editWorkspace(we *protocol.WorkspaceEdit) error {
+// workspaceChanges returns the edits of the workspace edit keyed by
+// document URI, or nil if there are no changes to apply.
+func workspaceChanges(we *protocol.WorkspaceEdit) map[string][]protocol.TextEdit {
 	if we == nil {
 		return nil // no changes to apply
 	}
@@ -236,6 +329,14 @@ func editWorkspace(we *protocol.WorkspaceEdit) error {
 	if we.Changes == nil {
 		return nil // no changes to apply
 	}
+	return *we.Changes
+}
+
+func editWorkspace(we *protocol.WorkspaceEdit) error {
+	changes := workspaceChanges(we)
+	if changes == nil {
+		return nil // no changes to apply
+	}
 
 	wins, err := acme.Windows()
 	if err != nil {
@@ -246,13 +347,13 @@ func editWorkspace(we *protocol.WorkspaceEdit) error {
 		winid[info.Name] = info.ID
 	}
 
-	for uri := range *we.Changes {
+	for uri := range changes {
 		fname := text.ToPath(uri)
 		if _, ok := winid[fname]; !ok {
 			return fmt.Errorf("%v: not open in acme", fname)
 		}
 	}
-	for uri, edits := range *we.Changes {
+	for uri, edits := range changes {
 		fname := text.ToPath(uri)
 		id := winid[fname]
 		w, err := acmeutil.OpenWin(id)
@@ -1,10 +1,14 @@
 package acmelsp
 
 import (
+	"bytes"
+	"context"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -12,6 +16,8 @@ import (
 	"github.com/tw4452852/acme-lsp/internal/lsp"
 	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -43,6 +49,124 @@ func TestPlumbLocation(t *testing.T) {
 	}
 }
 
+func TestDocumentLinkTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TODO: failing on windows due to file path issues")
+	}
+
+	for _, tc := range []struct {
+		target, want string
+	}{
+		{"https://pkg.go.dev/fmt", "https://pkg.go.dev/fmt"},
+		{"file:///usr/include/stdio.h", "/usr/include/stdio.h:1:1-1:1"},
+		{"file:///home/gopher/main.go#L10", "/home/gopher/main.go:10:1-10:1"},
+		{"file:///home/gopher/main.go#L10,5", "/home/gopher/main.go:10:5-10:5"},
+		{"file:///home/gopher/main.go#bad", "/home/gopher/main.go:1:1-1:1"},
+	} {
+		t.Run(tc.target, func(t *testing.T) {
+			got := documentLinkTarget(tc.target)
+			if got != tc.want {
+				t.Errorf("documentLinkTarget(%q) is %q; want %q", tc.target, got, tc.want)
+			}
+		})
+	}
+}
+
+// linkServer returns document links, which fail
+// to resolve if their tooltip is "bad".
+type linkServer struct {
+	proxy.Server
+	links []protocol.DocumentLink
+}
+
+func (s *linkServer) DocumentLink(context.Context, *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	return s.links, nil
+}
+
+func (s *linkServer) ResolveDocumentLinkOnDocument(ctx context.Context, params *proxy.ResolveDocumentLinkOnDocumentParams) (*protocol.DocumentLink, error) {
+	l := params.DocumentLink
+	if l.Tooltip == "bad" {
+		return nil, fmt.Errorf("resolve failed")
+	}
+	l.Target = "https://example.com/"
+	return &l, nil
+}
+
+func TestDocumentLinkUnresolved(t *testing.T) {
+	link := func(line float64, tooltip string) protocol.DocumentLink {
+		return protocol.DocumentLink{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line},
+				End:   protocol.Position{Line: line, Character: 3},
+			},
+			Tooltip: tooltip,
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	rc := NewRemoteCmd(&linkServer{
+		links: []protocol.DocumentLink{link(0, "bad"), link(1, "")},
+	}, 0)
+	rc.file = &headlessFile{
+		Buffer: text.NewBuffer("/home/gopher/main.go", []byte("abc\ndef\n")),
+	}
+	rc.Stdout = &stdout
+	rc.Stderr = &stderr
+	if err := rc.DocumentLink(context.Background()); err != nil {
+		t.Fatalf("DocumentLink failed: %v", err)
+	}
+	want := "/home/gopher/main.go:1:1-1:4: (unresolved)\n" +
+		"/home/gopher/main.go:2:1-2:4: https://example.com/\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("DocumentLink printed %q; want %q", got, want)
+	}
+	if !strings.Contains(stderr.String(), "resolve failed") {
+		t.Errorf("DocumentLink reported %q; want the resolve error", stderr.String())
+	}
+}
+
+func TestFormatSignatureHelp(t *testing.T) {
+	sh := &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{
+			{
+				Label: "f(a int)",
+			},
+			{
+				Label: "f(a int, b string) error",
+				Documentation: &protocol.MarkupContent{
+					Kind:  protocol.PlainText,
+					Value: "f does something.\nIt may fail.\n",
+				},
+				Parameters: []protocol.ParameterInformation{
+					{
+						Label: protocol.ParameterLabel{Offsets: []uint32{2, 7}},
+					},
+					{
+						Label: protocol.ParameterLabel{Label: "b string"},
+						Documentation: &protocol.MarkupContent{
+							Kind:  protocol.PlainText,
+							Value: "b is the name.",
+						},
+					},
+				},
+			},
+		},
+		ActiveSignature: 1,
+		ActiveParameter: 1,
+	}
+	want := "f(a int, «b string») error\n" +
+		"\tf does something.\n" +
+		"\tIt may fail.\n" +
+		"\tb string: b is the name.\n" +
+		"\n" +
+		"f(a int)\n"
+	if got := formatSignatureHelp(sh); got != want {
+		t.Errorf("formatted signature help is %q; want %q", got, want)
+	}
+	if got := formatSignatureHelp(&protocol.SignatureHelp{}); got != "" {
+		t.Errorf("formatted empty signature help is %q; want empty", got)
+	}
+}
+
 func TestParseFlagSet(t *testing.T) {
 	tt := []struct {
 		name       string
@@ -11,6 +11,7 @@ import (
 
 	"github.com/tw4452852/acme-lsp/internal/acme"
 	"github.com/tw4452852/acme-lsp/internal/acmeutil"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
 	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
 	"github.com/tw4452852/acme-lsp/internal/lsp/text"
@@ -72,49 +73,154 @@ func (fw *focusWin) SetQ0() bool {
 	return true
 }
 
+// assistDelay is how long a window must stay unchanged after an edit
+// before the assist window is updated.
+const assistDelay = 100 * time.Millisecond
+
+// winWatcher watches the event file of the focused window for edits.
+//
+// The window's files are opened over their own connection to acme,
+// because acme doesn't interrupt a read of the event file when the file is
+// closed. Hanging up the connection does, and acme restores the window's
+// Undo, Redo and Put tag commands, which it hides while the event file is
+// open, once the window loses focus.
+type winWatcher struct {
+	w      *acmeutil.Win
+	edited chan struct{} // signaled when text is inserted or deleted
+}
+
+// watchWin starts watching the events of window id.
+// It fails if the window's event file can't be opened.
+func watchWin(id int) (*winWatcher, error) {
+	w, err := acmeutil.OpenWinConn(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.OpenEvent(); err != nil {
+		w.CloseFiles()
+		return nil, err
+	}
+	ww := &winWatcher{
+		w:      w,
+		edited: make(chan struct{}, 1),
+	}
+	go ww.run()
+	return ww, nil
+}
+
+// close stops watching the window.
+func (ww *winWatcher) close() {
+	ww.w.Hangup()
+}
+
+// run reads the window events until the watcher is closed or the window
+// is deleted. Edits are signaled on ww.edited. Other events are written
+// back for acme to handle.
+func (ww *winWatcher) run() {
+	defer ww.w.CloseFiles()
+	for {
+		ev, err := ww.w.ReadEvent()
+		if err != nil {
+			return
+		}
+		switch ev.C2 {
+		case 'I', 'D': // insert, delete
+			select {
+			case ww.edited <- struct{}{}:
+			default: // a notification is already pending
+			}
+		default:
+			ww.w.WriteEvent(ev)
+		}
+	}
+}
+
+// notifyPosChange sends the focused window to ch after it's focused,
+// edited or saved, if its cursor position has changed or it was saved.
+// Focus changes and saves are read from acme's log file, and edits from the
+// event file of the focused window, which is only open while the window is
+// focused. Acme sends no event when the cursor is moved by clicking, so such
+// moves are noticed at the next edit or when the window is focused again.
 func notifyPosChange(sm ServerMatcher, ch chan<- *focusWin) {
 	fw := newFocusWin()
 	logch := make(chan *acme.LogEvent)
 	go watchLog(logch)
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
 
-	pos := make(map[int]int) // winid -> q0
+	pos := make(map[int]int)   // winid -> q0
+	var focused *winWatcher    // watcher of the focused window
+	var delay <-chan time.Time // debounces changes
+
+	notify := func() {
+		if !fw.SetQ0() {
+			return
+		}
+		if q0, ok := pos[fw.id]; !ok || q0 != fw.q0 {
+			pos[fw.id] = fw.q0
+			ch <- &focusWin{ // send a copy
+				id:   fw.id,
+				q0:   fw.q0,
+				name: fw.name,
+			}
+		}
+	}
+	unfocus := func() {
+		if focused != nil {
+			focused.close()
+			focused = nil
+		}
+		fw.Reset()
+	}
 
 	for {
+		var edited <-chan struct{}
+		if focused != nil {
+			edited = focused.edited
+		}
+
 		select {
 		case ev := <-logch:
 			_, found, err := sm.ServerMatch(context.Background(), ev.Name)
 			if found && err == nil && ev.Op == "focus" {
-				// TODO(fhs): we should really make use of context
-				// and cancel outstanding rpc requests on previously focused window.
+				unfocus()
 				fw.id = ev.ID
 				fw.name = ev.Name
-			} else {
-				fw.Reset()
-			}
-
-		case <-ticker.C:
-			if fw.SetQ0() && pos[fw.id] != fw.q0 {
-				pos[fw.id] = fw.q0
-				ch <- &focusWin{ // send a copy
-					id:   fw.id,
-					q0:   fw.q0,
-					name: fw.name,
+				if ww, err := watchWin(fw.id); err != nil {
+					dprintf("failed to watch events of window %v: %v\n", fw.id, err)
+				} else {
+					focused = ww
 				}
+				delay = time.After(assistDelay)
+			} else if ev.Op == "put" && ev.ID == fw.id {
+				// Notify even if the position hasn't changed.
+				delete(pos, fw.id)
+				delay = time.After(assistDelay)
+			} else if ev.Op == "focus" || ev.Op == "del" && ev.ID == fw.id {
+				unfocus()
 			}
+			if ev.Op == "del" {
+				delete(pos, ev.ID)
+			}
+
+		case <-edited:
+			delay = time.After(assistDelay)
+
+		case <-delay:
+			delay = nil
+			notify()
 		}
 	}
 }
 
 type outputWin struct {
 	*acmeutil.Win
-	body  io.Writer
-	event <-chan *acme.Event
-	sm    ServerMatcher
+	body    io.Writer
+	event   <-chan *acme.Event
+	sm      ServerMatcher
+	cfg     *config.Config          // settings of the commands run for the window
+	sigHelp *protocol.SignatureHelp // signature help being shown
 }
 
-func newOutputWin(sm ServerMatcher, name string) (*outputWin, error) {
+func newOutputWin(sm ServerMatcher, name string, cfg *config.Config) (*outputWin, error) {
 	w, err := acmeutil.NewWin()
 	if err != nil {
 		return nil, err
@@ -125,6 +231,7 @@ func newOutputWin(sm ServerMatcher, name string) (*outputWin, error) {
 		body:  w.FileReadWriter("body"),
 		event: w.EventChan(),
 		sm:    sm,
+		cfg:   cfg,
 	}, nil
 }
 
@@ -171,18 +278,79 @@ func isIdentifier(r rune) bool {
 	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
 }
 
-func helpType(left, right rune) string {
+// isTrigger reports whether r is one of the trigger characters.
+func isTrigger(r rune, triggers []string) bool {
+	for _, t := range triggers {
+		if t == string(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// helpType returns the assist command to run when the cursor is between
+// runes left and right, based on the trigger characters advertised in
+// the server capabilities. Signature help triggered earlier is still
+// active if sigActive is true.
+func helpType(left, right rune, caps *protocol.ServerCapabilities, sigActive bool) string {
 	if unicode.IsSpace(left) && unicode.IsSpace(right) {
 		return ""
 	}
+	if sp := caps.SignatureHelpProvider; sp != nil {
+		if isTrigger(left, sp.TriggerCharacters) || sigActive && isTrigger(left, sp.RetriggerCharacters) {
+			return "sig"
+		}
+	}
+	cp := caps.CompletionProvider
+	if cp != nil && isTrigger(left, cp.TriggerCharacters) {
+		return "comp"
+	}
 	if isIdentifier(left) && isIdentifier(right) {
 		return "hov"
 	}
-	switch left {
-	case '(', '[', '<', '{':
+	if cp != nil && isIdentifier(left) {
+		return "comp"
+	}
+	if sigActive {
 		return "sig"
 	}
-	return "comp"
+	return ""
+}
+
+// completionContext returns the context of a completion
+// request made with the cursor right after rune left.
+func completionContext(left rune, caps *protocol.ServerCapabilities) *protocol.CompletionContext {
+	if cp := caps.CompletionProvider; cp != nil && isTrigger(left, cp.TriggerCharacters) {
+		return &protocol.CompletionContext{
+			TriggerKind:      protocol.TriggerCharacter,
+			TriggerCharacter: string(left),
+		}
+	}
+	return &protocol.CompletionContext{
+		TriggerKind: protocol.Invoked,
+	}
+}
+
+// signatureHelpContext returns the context of a signature help request
+// made with the cursor right after rune left. The active signature help
+// is the one currently shown, or nil.
+func signatureHelpContext(left rune, caps *protocol.ServerCapabilities, active *protocol.SignatureHelp) *protocol.SignatureHelpContext {
+	shc := &protocol.SignatureHelpContext{
+		TriggerKind:         protocol.SigInvoked,
+		IsRetrigger:         active != nil,
+		ActiveSignatureHelp: active,
+	}
+	if sp := caps.SignatureHelpProvider; sp != nil {
+		if isTrigger(left, sp.TriggerCharacters) || active != nil && isTrigger(left, sp.RetriggerCharacters) {
+			shc.TriggerKind = protocol.SigTriggerCharacter
+			shc.TriggerCharacter = string(left)
+			return shc
+		}
+	}
+	if active != nil {
+		shc.TriggerKind = protocol.ContentChange
+	}
+	return shc
 }
 
 func dprintf(format string, args ...interface{}) {
@@ -192,14 +360,25 @@ func dprintf(format string, args ...interface{}) {
 }
 
 // Update writes result of cmd to output window.
-func (w *outputWin) Update(fw *focusWin, server proxy.Server, cmd string) {
+// Requests sent to the server are canceled when ctx is done.
+func (w *outputWin) Update(ctx context.Context, fw *focusWin, server proxy.Server, cmd string) {
+	// The runes around the cursor are only required for auto-detection.
+	// Otherwise, they're used to find the trigger character, if any.
+	left, right, err := readLeftRight(fw.id, fw.q0)
+	if err != nil && cmd == "auto" {
+		dprintf("read left/right rune: %v\n", err)
+		return
+	}
+	ir, err := server.InitializeResult(ctx, &protocol.TextDocumentIdentifier{
+		URI: text.ToURI(fw.name),
+	})
+	if err != nil {
+		dprintf("InitializeResult failed: %v\n", err)
+		return
+	}
+	caps := &ir.Capabilities
 	if cmd == "auto" {
-		left, right, err := readLeftRight(fw.id, fw.q0)
-		if err != nil {
-			dprintf("read left/right rune: %v\n", err)
-			return
-		}
-		cmd = helpType(left, right)
+		cmd = helpType(left, right, caps, w.sigHelp != nil)
 		if cmd == "" {
 			return
 		}
@@ -208,48 +387,94 @@ func (w *outputWin) Update(fw *focusWin, server proxy.Server, cmd string) {
 	rc := NewRemoteCmd(server, fw.id)
 	rc.Stdout = w.body
 	rc.Stderr = w.body
-	ctx := context.Background()
+	rc.CompletionMatcher = w.cfg.CompletionMatcher
 
 	// Assume file is already opened by file management.
-	err := rc.DidChange(ctx)
+	err = rc.DidChange(ctx)
 	if err != nil {
 		dprintf("DidChange failed: %v\n", err)
 		return
 	}
 
 	w.Clear()
+	if _, path, err := rc.enclosingSymbols(ctx); err != nil {
+		dprintf("DocumentSymbol failed: %v\n", err)
+	} else if len(path) > 0 {
+		fmt.Fprintf(w.body, "%v\n\n", formatBreadcrumb(path))
+	}
+	sigHelp := w.sigHelp
+	w.sigHelp = nil
 	switch cmd {
 	case "comp":
-		err := rc.Completion(ctx, false)
+		err := rc.completion(ctx, false, completionContext(left, caps))
 		if err != nil {
 			dprintf("Completion failed: %v\n", err)
 		}
 
 	case "sig":
-		err = rc.SignatureHelp(ctx)
+		sh, err := rc.signatureHelp(ctx, signatureHelpContext(left, caps, sigHelp))
 		if err != nil {
 			dprintf("SignatureHelp failed: %v\n", err)
+		} else if sh != nil && len(sh.Signatures) > 0 {
+			w.sigHelp = sh
 		}
 	case "hov":
 		err = rc.Hover(ctx)
 		if err != nil {
 			dprintf("Hover failed: %v\n", err)
 		}
+	case "hints":
+		err = rc.InlayHints(ctx)
+		if err != nil {
+			dprintf("InlayHints failed: %v\n", err)
+		}
 	default:
 		log.Fatalf("invalid command %q\n", cmd)
 	}
 	w.Ctl("clean")
 }
 
+// assistUpdate is an update of the assist window running in the background.
+type assistUpdate struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startUpdate runs Update in the background.
+func (w *outputWin) startUpdate(fw *focusWin, server proxy.Server, cmd string) *assistUpdate {
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &assistUpdate{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(u.done)
+		w.Update(ctx, fw, server, cmd)
+	}()
+	return u
+}
+
+// Stop cancels the update and waits for it to finish.
+// It does nothing if u is nil.
+func (u *assistUpdate) Stop() {
+	if u == nil {
+		return
+	}
+	u.cancel()
+	<-u.done
+}
+
 // Assist creates an acme window where output of cmd is written after each
-// cursor position change in acme. Cmd is either "comp", "sig", "hov", or "auto"
+// cursor position change in acme (see notifyPosChange). Cmd is either "comp", "sig", "hov", or "auto"
 // for completion, signature help, hover, or auto-detection of the former three.
-func Assist(sm ServerMatcher, cmd string) error {
+// Cmd can also be "hints" for inlay hints, which is never auto-detected.
+// Settings such as the completion matcher are taken from cfg.
+func Assist(sm ServerMatcher, cmd string, cfg *config.Config) error {
 	name := "/LSP/assist"
 	if cmd != "auto" {
 		name += "/" + cmd
 	}
-	w, err := newOutputWin(sm, name)
+	w, err := newOutputWin(sm, name, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create acme window: %v", err)
 	}
@@ -258,16 +483,23 @@ func Assist(sm ServerMatcher, cmd string) error {
 	fch := make(chan *focusWin)
 	go notifyPosChange(sm, fch)
 
+	var update *assistUpdate
+	defer func() {
+		update.Stop()
+	}()
+
 loop:
 	for {
 		select {
 		case fw := <-fch:
+			update.Stop()
+			update = nil
 			server, found, err := sm.ServerMatch(context.Background(), fw.name)
 			if err != nil {
 				log.Printf("failed to start language server: %v\n", err)
 			}
 			if found {
-				w.Update(fw, server, cmd)
+				update = w.startUpdate(fw, server, cmd)
 			}
 
 		case ev := <-w.event:
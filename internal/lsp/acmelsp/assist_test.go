@@ -0,0 +1,142 @@
+package acmelsp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func TestHelpType(t *testing.T) {
+	caps := &protocol.ServerCapabilities{
+		CompletionProvider: &protocol.CompletionOptions{
+			TriggerCharacters: []string{".", ":"},
+		},
+		SignatureHelpProvider: &protocol.SignatureHelpOptions{
+			TriggerCharacters:   []string{"("},
+			RetriggerCharacters: []string{","},
+		},
+	}
+	for _, tc := range []struct {
+		left, right rune
+		sigActive   bool
+		want        string
+	}{
+		{' ', '\n', false, ""},
+		{'.', '\n', false, "comp"},
+		{':', ')', false, "comp"},
+		{'(', ')', false, "sig"},
+		{',', ')', false, ""},
+		{',', ')', true, "sig"},
+		{' ', ')', true, "sig"},
+		{'a', 'b', false, "hov"},
+		{'a', '\n', false, "comp"},
+		{'{', '}', false, ""},
+	} {
+		got := helpType(tc.left, tc.right, caps, tc.sigActive)
+		if got != tc.want {
+			t.Errorf("helpType(%q, %q, sigActive=%v) is %q; want %q",
+				tc.left, tc.right, tc.sigActive, got, tc.want)
+		}
+	}
+	if got := helpType('a', '\n', &protocol.ServerCapabilities{}, false); got != "" {
+		t.Errorf("helpType without completion provider is %q; want empty", got)
+	}
+}
+
+func TestSignatureHelpContext(t *testing.T) {
+	caps := &protocol.ServerCapabilities{
+		SignatureHelpProvider: &protocol.SignatureHelpOptions{
+			TriggerCharacters:   []string{"("},
+			RetriggerCharacters: []string{","},
+		},
+	}
+	active := &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{{Label: "f(a, b int)"}},
+	}
+	for _, tc := range []struct {
+		left   rune
+		active *protocol.SignatureHelp
+		want   *protocol.SignatureHelpContext
+	}{
+		{
+			'(', nil,
+			&protocol.SignatureHelpContext{
+				TriggerKind:      protocol.SigTriggerCharacter,
+				TriggerCharacter: "(",
+			},
+		},
+		{
+			',', nil,
+			&protocol.SignatureHelpContext{
+				TriggerKind: protocol.SigInvoked,
+			},
+		},
+		{
+			',', active,
+			&protocol.SignatureHelpContext{
+				TriggerKind:         protocol.SigTriggerCharacter,
+				TriggerCharacter:    ",",
+				IsRetrigger:         true,
+				ActiveSignatureHelp: active,
+			},
+		},
+		{
+			'x', active,
+			&protocol.SignatureHelpContext{
+				TriggerKind:         protocol.ContentChange,
+				IsRetrigger:         true,
+				ActiveSignatureHelp: active,
+			},
+		},
+	} {
+		got := signatureHelpContext(tc.left, caps, tc.active)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("signatureHelpContext(%q, %v) is %+v; want %+v", tc.left, tc.active, got, tc.want)
+		}
+	}
+}
+
+func TestInlayHintRange(t *testing.T) {
+	long := strings.Repeat("x\n", 2*inlayHintLines+10)
+	for _, tc := range []struct {
+		name string
+		body string
+		line int
+		want protocol.Range
+	}{
+		{
+			name: "middle",
+			body: long,
+			line: inlayHintLines + 2,
+			want: protocol.Range{
+				Start: protocol.Position{Line: 2},
+				End:   protocol.Position{Line: 2*inlayHintLines + 3},
+			},
+		},
+		{
+			name: "trailing newline",
+			body: "a\nb\n",
+			line: 1,
+			want: protocol.Range{
+				End: protocol.Position{Line: 2},
+			},
+		},
+		{
+			name: "no trailing newline",
+			body: "a\nbäc",
+			line: 1,
+			want: protocol.Range{
+				End: protocol.Position{Line: 1, Character: 3},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := inlayHintRange([]byte(tc.body), tc.line)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("range is %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
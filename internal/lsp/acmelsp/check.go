@@ -0,0 +1,327 @@
+package acmelsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+// Formats that can be used with PrintDiagnostics.
+const (
+	PlainFormat       = "plain"       // same as the /LSP/Diagnostics window
+	JSONFormat        = "json"        // list of textDocument/publishDiagnostics params
+	SARIFFormat       = "sarif"       // SARIF 2.1.0 log
+	ErrorformatFormat = "errorformat" // file:line:col: severity: message
+)
+
+// diagCollector implements DiagnosticsWriter.
+// It keeps the latest diagnostics of each document.
+type diagCollector struct {
+	diags    map[protocol.DocumentURI][]protocol.Diagnostic
+	activity chan struct{} // signaled when diagnostics are published
+	mu       sync.Mutex
+}
+
+func newDiagCollector() *diagCollector {
+	return &diagCollector{
+		diags:    make(map[protocol.DocumentURI][]protocol.Diagnostic),
+		activity: make(chan struct{}, 1),
+	}
+}
+
+func (c *diagCollector) WriteDiagnostics(params *protocol.PublishDiagnosticsParams) {
+	c.mu.Lock()
+	if len(params.Diagnostics) == 0 {
+		delete(c.diags, params.URI)
+	} else {
+		c.diags[params.URI] = params.Diagnostics
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.activity <- struct{}{}:
+	default:
+	}
+}
+
+// list returns the diagnostics sorted by URI.
+func (c *diagCollector) list() []protocol.PublishDiagnosticsParams {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := []protocol.PublishDiagnosticsParams{}
+	for uri, diags := range c.diags {
+		list = append(list, protocol.PublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: diags,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].URI < list[j].URI
+	})
+	return list
+}
+
+// Check starts the LSP servers for the files within the workspace
+// directories of cfg and opens all the files. It returns the diagnostics
+// published once the servers have been quiet (i.e. no diagnostics were
+// published) for the given duration. Progress notifications aren't used,
+// since the client doesn't support them. Hidden directories are skipped.
+//
+// Files which can't be read, or whose server can't be started, are
+// reported to the log and skipped. In that case, or if ctx is done
+// before the servers are quiet, the diagnostics collected so far are
+// returned along with an error.
+func Check(ctx context.Context, cfg *config.Config, quiet time.Duration) ([]protocol.PublishDiagnosticsParams, error) {
+	dc := newDiagCollector()
+	ss, err := NewServerSet(cfg, dc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server set: %v", err)
+	}
+	// Stop the servers instead of closing the connections to them,
+	// so that they aren't restarted when they exit.
+	defer ss.stopAll(context.Background())
+
+	seen := make(map[string]bool)
+	failed := make(map[*ServerInfo]bool) // servers which couldn't be started
+	nfailed := 0
+	for _, dir := range cfg.WorkspaceDirectories {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+			if err != nil {
+				log.Printf("%v", err)
+				nfailed++
+				return nil
+			}
+			if fi.IsDir() {
+				if name != dir && strings.HasPrefix(fi.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fi.Mode().IsRegular() || seen[name] {
+				return nil
+			}
+			seen[name] = true
+
+			info := ss.MatchFile(name)
+			if info == nil {
+				return nil
+			}
+			if failed[info] {
+				nfailed++
+				return nil
+			}
+			srv, err := info.start(ss.ClientConfig(info))
+			if err != nil {
+				log.Printf("could not start language server for %v: %v", name, err)
+				failed[info] = true
+				nfailed++
+				return nil
+			}
+			if err := checkOpen(ctx, srv, name); err != nil {
+				log.Printf("could not check %v: %v", name, err)
+				nfailed++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		select {
+		case <-dc.activity:
+		case <-time.After(quiet):
+			if nfailed > 0 {
+				return dc.list(), fmt.Errorf("%v files could not be checked", nfailed)
+			}
+			return dc.list(), nil
+		case <-ctx.Done():
+			return dc.list(), fmt.Errorf("waiting for diagnostics: %v", ctx.Err())
+		}
+	}
+}
+
+// checkOpen opens the named file with srv.
+func checkOpen(ctx context.Context, srv *Server, name string) error {
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return lsp.DidOpen(ctx, srv.Client, name, srv.Client.cfg.FilenameHandler.LanguageID, body)
+}
+
+// diagnosticSeverity returns the severity of d. A diagnostic
+// without a severity is considered an error.
+func diagnosticSeverity(d *protocol.Diagnostic) protocol.DiagnosticSeverity {
+	if d.Severity == 0 {
+		return protocol.SeverityError
+	}
+	return d.Severity
+}
+
+// ParseSeverity parses the name of a diagnostic severity
+// (e.g. "warning"), ignoring case.
+func ParseSeverity(s string) (protocol.DiagnosticSeverity, error) {
+	for _, sev := range []protocol.DiagnosticSeverity{
+		protocol.SeverityError,
+		protocol.SeverityWarning,
+		protocol.SeverityInformation,
+		protocol.SeverityHint,
+	} {
+		if strings.EqualFold(s, fmt.Sprint(sev)) {
+			return sev, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown diagnostic severity %q", s)
+}
+
+// CountDiagnostics returns the number of diagnostics
+// which are at least as severe as sev.
+func CountDiagnostics(list []protocol.PublishDiagnosticsParams, sev protocol.DiagnosticSeverity) int {
+	n := 0
+	for _, p := range list {
+		for i := range p.Diagnostics {
+			if diagnosticSeverity(&p.Diagnostics[i]) <= sev {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// PrintDiagnostics writes the diagnostics to w in the given format.
+func PrintDiagnostics(w io.Writer, format string, list []protocol.PublishDiagnosticsParams) error {
+	switch format {
+	case PlainFormat:
+		for _, p := range list {
+			for _, d := range p.Diagnostics {
+				loc := &protocol.Location{
+					URI:   p.URI,
+					Range: d.Range,
+				}
+				fmt.Fprintf(w, "%v: %v\n", lsp.LocationLink(loc), d.Message)
+			}
+		}
+		return nil
+
+	case ErrorformatFormat:
+		for _, p := range list {
+			for i, d := range p.Diagnostics {
+				fmt.Fprintf(w, "%v:%v:%v: %v: %v\n", text.ToPath(p.URI),
+					d.Range.Start.Line+1, d.Range.Start.Character+1,
+					strings.ToLower(fmt.Sprint(diagnosticSeverity(&p.Diagnostics[i]))),
+					strings.Replace(d.Message, "\n", " ", -1))
+			}
+		}
+		return nil
+
+	case JSONFormat:
+		return PrintJSON(w, list)
+
+	case SARIFFormat:
+		b, err := json.MarshalIndent(sarifLog(list), "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	return fmt.Errorf("unknown diagnostics format %q", format)
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region sarifRegion `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId,omitempty"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name string `json:"name"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+// sarifLog converts the diagnostics to a SARIF 2.1.0 log
+// (see https://docs.oasis-open.org/sarif/sarif/v2.1.0/).
+func sarifLog(list []protocol.PublishDiagnosticsParams) interface{} {
+	var run sarifRun
+	run.Tool.Driver.Name = "acme-lsp"
+	run.Results = []sarifResult{}
+	for _, p := range list {
+		for i, d := range p.Diagnostics {
+			var r sarifResult
+			switch diagnosticSeverity(&p.Diagnostics[i]) {
+			case protocol.SeverityError:
+				r.Level = "error"
+			case protocol.SeverityWarning:
+				r.Level = "warning"
+			default:
+				r.Level = "note"
+			}
+			if d.Code != nil {
+				r.RuleID = fmt.Sprint(d.Code)
+			} else {
+				r.RuleID = d.Source
+			}
+			r.Message.Text = d.Message
+
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = string(p.URI)
+			loc.PhysicalLocation.Region = sarifRegion{
+				StartLine:   int(d.Range.Start.Line) + 1,
+				StartColumn: int(d.Range.Start.Character) + 1,
+				EndLine:     int(d.Range.End.Line) + 1,
+				EndColumn:   int(d.Range.End.Character) + 1,
+			}
+			r.Locations = []sarifLocation{loc}
+			run.Results = append(run.Results, r)
+		}
+	}
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs":    []sarifRun{run},
+	}
+}
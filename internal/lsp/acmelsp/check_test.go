@@ -0,0 +1,172 @@
+package acmelsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+var checkDiagnostics = []protocol.PublishDiagnosticsParams{
+	{
+		URI: "file:///home/gopher/main.go",
+		Diagnostics: []protocol.Diagnostic{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 4, Character: 1},
+					End:   protocol.Position{Line: 4, Character: 4},
+				},
+				Severity: protocol.SeverityError,
+				Source:   "compiler",
+				Message:  "undeclared name: fmt",
+			},
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 9, Character: 0},
+					End:   protocol.Position{Line: 9, Character: 2},
+				},
+				Severity: protocol.SeverityHint,
+				Message:  "unused\nresult",
+			},
+		},
+	},
+}
+
+func TestPrintDiagnostics(t *testing.T) {
+	for _, tc := range []struct {
+		format, want string
+	}{
+		{
+			PlainFormat,
+			"/home/gopher/main.go:5:2-5:5: undeclared name: fmt\n" +
+				"/home/gopher/main.go:10:1-10:3: unused\nresult\n",
+		},
+		{
+			ErrorformatFormat,
+			"/home/gopher/main.go:5:2: error: undeclared name: fmt\n" +
+				"/home/gopher/main.go:10:1: hint: unused result\n",
+		},
+	} {
+		var buf bytes.Buffer
+		if err := PrintDiagnostics(&buf, tc.format, checkDiagnostics); err != nil {
+			t.Fatalf("PrintDiagnostics failed for format %v: %v", tc.format, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("format %v output is %q; want %q", tc.format, got, tc.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PrintDiagnostics(&buf, "xml", checkDiagnostics); err == nil {
+		t.Errorf("PrintDiagnostics succeeded for unknown format")
+	}
+}
+
+func TestPrintDiagnosticsSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintDiagnostics(&buf, SARIFFormat, checkDiagnostics); err != nil {
+		t.Fatalf("PrintDiagnostics failed: %v", err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							StartLine, StartColumn int
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("failed to decode SARIF log: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("unexpected SARIF log:\n%s", buf.Bytes())
+	}
+	r := log.Runs[0].Results[0]
+	if r.RuleID != "compiler" || r.Level != "error" {
+		t.Errorf("result has rule %q and level %q; want \"compiler\" and \"error\"", r.RuleID, r.Level)
+	}
+	if reg := r.Locations[0].PhysicalLocation.Region; reg.StartLine != 5 || reg.StartColumn != 2 {
+		t.Errorf("result starts at %v:%v; want 5:2", reg.StartLine, reg.StartColumn)
+	}
+	if l := log.Runs[0].Results[1].Level; l != "note" {
+		t.Errorf("hint has level %q; want \"note\"", l)
+	}
+}
+
+func TestCountDiagnostics(t *testing.T) {
+	for _, tc := range []struct {
+		severity string
+		want     int
+	}{
+		{"error", 1},
+		{"Warning", 1},
+		{"hint", 2},
+	} {
+		sev, err := ParseSeverity(tc.severity)
+		if err != nil {
+			t.Fatalf("ParseSeverity failed: %v", err)
+		}
+		if got := CountDiagnostics(checkDiagnostics, sev); got != tc.want {
+			t.Errorf("%v diagnostics count is %v; want %v", tc.severity, got, tc.want)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Errorf("ParseSeverity succeeded for unknown severity")
+	}
+}
+
+func TestCheckServerNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acmelsp")
+	if err != nil {
+		t.Fatalf("TempDir failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.nolsp", "b.nolsp", "README"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	cfg := &config.Config{
+		File: config.File{
+			WorkspaceDirectories: []string{dir},
+			Servers: map[string]*config.Server{
+				"nolsp": {
+					Command: []string{"acme-lsp-test-no-such-server"},
+				},
+			},
+			FilenameHandlers: []config.FilenameHandler{
+				{
+					Pattern:   `\.nolsp$`,
+					ServerKey: "nolsp",
+				},
+			},
+		},
+	}
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	diags, err := Check(context.Background(), cfg, time.Millisecond)
+	if want := "2 files could not be checked"; err == nil || err.Error() != want {
+		t.Errorf("Check returned error %v; want %q", err, want)
+	}
+	if diags == nil || len(diags) != 0 {
+		t.Errorf("Check returned diagnostics %v; want none", diags)
+	}
+}
@@ -2,7 +2,9 @@ package acmelsp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -24,6 +26,7 @@ type DiagnosticsWriter interface {
 
 // clientHandler handles JSON-RPC requests and notifications.
 type clientHandler struct {
+	client     *Client
 	cfg        *ClientConfig
 	hideDiag   bool
 	diagWriter DiagnosticsWriter
@@ -32,6 +35,13 @@ type clientHandler struct {
 }
 
 func (h *clientHandler) ShowMessage(ctx context.Context, params *protocol.ShowMessageParams) error {
+	if h.cfg.MessageWriter != nil {
+		_, err := fmt.Fprintf(h.cfg.MessageWriter, "%v: %v\n", params.Type, params.Message)
+		if err == nil {
+			return nil
+		}
+		dprintf("failed to write message: %v\n", err)
+	}
 	log.Printf("LSP %v: %v\n", params.Type, params.Message)
 	return nil
 }
@@ -80,7 +90,13 @@ func (h *clientHandler) ShowMessageRequest(context.Context, *protocol.ShowMessag
 	return nil, nil
 }
 
+// ApplyEdit applies the edit to acme windows, unless the server requests
+// it while executing a command for a LSP client (see Client.executeCommand),
+// in which case the edit is sent to that client.
 func (h *clientHandler) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
+	if editor := h.client.commandEditor(); editor != nil {
+		return editor.ApplyEdit(ctx, params)
+	}
 	err := editWorkspace(&params.Edit)
 	if err != nil {
 		return &protocol.ApplyWorkspaceEditResponse{Applied: false, FailureReason: err.Error()}, nil
@@ -96,6 +112,7 @@ type ClientConfig struct {
 	HideDiag      bool                       // don't write diagnostics to DiagWriter
 	RPCTrace      bool                       // print LSP rpc trace to stderr
 	DiagWriter    DiagnosticsWriter          // notification handler writes diagnostics here
+	MessageWriter io.Writer                  // notification handler writes messages here, if not nil
 	Workspaces    []protocol.WorkspaceFolder // initial workspace folders
 	Logger        *log.Logger
 }
@@ -105,6 +122,16 @@ type Client struct {
 	protocol.Server
 	initializeResult *protocol.InitializeResult
 	cfg              *ClientConfig
+	rpc              *jsonrpc2.Conn
+
+	docs map[protocol.DocumentURI]*document // open documents
+
+	// LSP client which is executing a command, or nil if it's acme.
+	// Commands are executed one at a time (see executeCommand).
+	editor protocol.Client
+	exec   sync.Mutex
+
+	mu sync.Mutex // protects initializeResult, docs and editor
 }
 
 func NewClient(conn net.Conn, cfg *ClientConfig) (*Client, error) {
@@ -122,6 +149,7 @@ func (c *Client) init(conn net.Conn, cfg *ClientConfig) error {
 		stream = protocol.LoggingStream(stream, os.Stderr)
 	}
 	ctx, rpc, server := protocol.NewClient(ctx, stream, &clientHandler{
+		client:     c,
 		cfg:        cfg,
 		hideDiag:   cfg.HideDiag,
 		diagWriter: cfg.DiagWriter,
@@ -138,6 +166,8 @@ func (c *Client) init(conn net.Conn, cfg *ClientConfig) error {
 	if err != nil {
 		return err
 	}
+	// Markdown is rendered to plain text before it's shown in acme.
+	markupKinds := []protocol.MarkupKind{protocol.Markdown, protocol.PlainText}
 	params := &protocol.InitializeParams{
 		RootURI: text.ToURI(d),
 		Capabilities: protocol.ClientCapabilities{
@@ -151,7 +181,22 @@ func (c *Client) init(conn net.Conn, cfg *ClientConfig) error {
 				DocumentSymbol: &protocol.DocumentSymbolClientCapabilities{
 					HierarchicalDocumentSymbolSupport: true,
 				},
-				Completion: &protocol.CompletionClientCapabilities{},
+				Completion: &protocol.CompletionClientCapabilities{
+					ContextSupport: true,
+				},
+				SignatureHelp: &protocol.SignatureHelpClientCapabilities{
+					SignatureInformation: &protocol.SignatureInformationCapabilities{
+						DocumentationFormat: markupKinds,
+						ParameterInformation: &protocol.ParameterInformationCapabilities{
+							LabelOffsetSupport: true,
+						},
+					},
+					ContextSupport: true,
+				},
+				Hover: &protocol.HoverClientCapabilities{
+					ContentFormat: markupKinds,
+				},
+				InlayHint: &protocol.InlayHintClientCapabilities{},
 			},
 		},
 		WorkspaceFolders:      cfg.Workspaces,
@@ -162,6 +207,7 @@ func (c *Client) init(conn net.Conn, cfg *ClientConfig) error {
 	params.Capabilities.TextDocument.CodeAction.CodeActionLiteralSupport.CodeActionKind.ValueSet =
 		[]protocol.CodeActionKind{protocol.SourceOrganizeImports}
 	params.Capabilities.TextDocument.Completion.CompletionItem.SnippetSupport = true
+	params.Capabilities.TextDocument.Completion.CompletionItem.DocumentationFormat = markupKinds
 
 	var result protocol.InitializeResult
 	if err := rpc.Call(ctx, "initialize", params, &result); err != nil {
@@ -171,13 +217,28 @@ func (c *Client) init(conn net.Conn, cfg *ClientConfig) error {
 		return fmt.Errorf("initialized failed: %v", err)
 	}
 	c.Server = server
+	c.rpc = rpc
+
+	c.mu.Lock()
 	c.initializeResult = &result
+	// A restarted server doesn't know about any documents.
+	c.docs = make(map[protocol.DocumentURI]*document)
+	c.mu.Unlock()
 	return nil
 }
 
+// initResult returns the result of the initialize request,
+// which changes when the server is restarted.
+func (c *Client) initResult() *protocol.InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.initializeResult
+}
+
 // InitializeResult implements proxy.Server.
 func (c *Client) InitializeResult(context.Context, *protocol.TextDocumentIdentifier) (*protocol.InitializeResult, error) {
-	return c.initializeResult, nil
+	return c.initResult(), nil
 }
 
 // Version exists only to implement proxy.Server.
@@ -190,7 +251,197 @@ func (c *Client) WorkspaceFolders(context.Context) ([]protocol.WorkspaceFolder,
 	panic("intentionally not implemented")
 }
 
+// ExecuteCommand implements protocol.Server. Workspace edits
+// requested by the server meanwhile are applied to acme windows.
+func (c *Client) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	return c.executeCommand(ctx, params, nil)
+}
+
+// executeCommand executes the command. Workspace edits requested by
+// the server meanwhile are sent to editor, or applied to acme windows
+// if editor is nil. Commands are executed one at a time, since the
+// requests don't tell which command they're for.
+func (c *Client) executeCommand(ctx context.Context, params *protocol.ExecuteCommandParams, editor protocol.Client) (interface{}, error) {
+	c.exec.Lock()
+	defer c.exec.Unlock()
+
+	c.mu.Lock()
+	c.editor = editor
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.editor = nil
+		c.mu.Unlock()
+	}()
+	return c.Server.ExecuteCommand(ctx, params)
+}
+
+// commandEditor returns the LSP client which is executing a command,
+// or nil if it's acme or c is nil.
+func (c *Client) commandEditor() protocol.Client {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.editor
+}
+
 // ExecuteCommandOnDocument implements proxy.Server.
 func (s *Client) ExecuteCommandOnDocument(ctx context.Context, params *proxy.ExecuteCommandOnDocumentParams) (interface{}, error) {
-	return s.Server.ExecuteCommand(ctx, &params.ExecuteCommandParams)
+	return s.ExecuteCommand(ctx, &params.ExecuteCommandParams)
+}
+
+// ResolveCodeLensOnDocument implements proxy.Server.
+func (s *Client) ResolveCodeLensOnDocument(ctx context.Context, params *proxy.ResolveCodeLensOnDocumentParams) (*protocol.CodeLens, error) {
+	return s.Server.ResolveCodeLens(ctx, &params.CodeLens)
+}
+
+// ResolveDocumentLinkOnDocument implements proxy.Server.
+func (s *Client) ResolveDocumentLinkOnDocument(ctx context.Context, params *proxy.ResolveDocumentLinkOnDocumentParams) (*protocol.DocumentLink, error) {
+	return s.Server.ResolveDocumentLink(ctx, &params.DocumentLink)
+}
+
+// ResolveCompletionItemOnDocument implements proxy.Server.
+func (s *Client) ResolveCompletionItemOnDocument(ctx context.Context, params *proxy.ResolveCompletionItemOnDocumentParams) (*protocol.CompletionItem, error) {
+	return s.Server.Resolve(ctx, &params.CompletionItem)
+}
+
+// SetTabStops implements proxy.Server. It does nothing because
+// tab stops are only remembered by the acme-lsp proxy server.
+func (s *Client) SetTabStops(context.Context, *proxy.TabStopsParams) error {
+	return nil
+}
+
+// NextTabStop implements proxy.Server.
+func (s *Client) NextTabStop(context.Context, *proxy.NextTabStopParams) (*text.TabStop, error) {
+	return nil, fmt.Errorf("tab stops are only supported by acme-lsp")
+}
+
+// AddJump implements proxy.Server. It does nothing because
+// the navigation history is only kept by the acme-lsp proxy server.
+func (s *Client) AddJump(context.Context, *proxy.AddJumpParams) error {
+	return nil
+}
+
+// Jump implements proxy.Server.
+func (s *Client) Jump(context.Context, *proxy.JumpParams) (*protocol.Location, error) {
+	return nil, fmt.Errorf("navigation history is only supported by acme-lsp")
+}
+
+// Jumps implements proxy.Server.
+func (s *Client) Jumps(context.Context) (*proxy.JumpsResult, error) {
+	return nil, fmt.Errorf("navigation history is only supported by acme-lsp")
+}
+
+// ShowResults implements proxy.Server.
+func (s *Client) ShowResults(context.Context, *proxy.ShowResultsParams) error {
+	return fmt.Errorf("result windows are only supported by acme-lsp")
+}
+
+// Status implements proxy.Server.
+func (s *Client) Status(context.Context) (*proxy.StatusResult, error) {
+	return nil, fmt.Errorf("server status is only supported by acme-lsp")
+}
+
+// RestartServer implements proxy.Server.
+func (s *Client) RestartServer(context.Context, *proxy.ServerParams) error {
+	return fmt.Errorf("restarting servers is only supported by acme-lsp")
+}
+
+// StopServer implements proxy.Server.
+func (s *Client) StopServer(context.Context, *proxy.ServerParams) error {
+	return fmt.Errorf("stopping servers is only supported by acme-lsp")
+}
+
+// DidOpen implements protocol.Server.
+// It keeps track of the document version and text.
+func (c *Client) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	if err := c.Server.DidOpen(ctx, params); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.docs[params.TextDocument.URI] = &document{
+		languageID: params.TextDocument.LanguageID,
+		version:    params.TextDocument.Version,
+		text:       params.TextDocument.Text,
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// DidChange implements protocol.Server. The document version is
+// incremented if the given version isn't newer than the current one,
+// since the server expects it to increase after each change.
+func (c *Client) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	c.mu.Lock()
+	uri := params.TextDocument.URI
+	doc, ok := c.docs[uri]
+	if !ok {
+		doc = &document{stale: true}
+		c.docs[uri] = doc
+	}
+	if params.TextDocument.Version <= doc.version {
+		params.TextDocument.Version = doc.version + 1
+	}
+	doc.version = params.TextDocument.Version
+	for _, ch := range params.ContentChanges {
+		if ch.Range != nil {
+			doc.text = ""
+			doc.stale = true
+			continue
+		}
+		doc.text = ch.Text
+		doc.stale = false
+	}
+	c.mu.Unlock()
+
+	return c.Server.DidChange(ctx, params)
+}
+
+// DidClose implements protocol.Server.
+// It forgets the document.
+func (c *Client) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	c.mu.Lock()
+	delete(c.docs, params.TextDocument.URI)
+	c.mu.Unlock()
+
+	return c.Server.DidClose(ctx, params)
+}
+
+// document is a document open in the LSP server.
+type document struct {
+	languageID string
+	version    float64
+	text       string
+	stale      bool // text is unknown because of an incremental change
+}
+
+// documents returns the open documents.
+func (c *Client) documents() map[protocol.DocumentURI]document {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := make(map[protocol.DocumentURI]document, len(c.docs))
+	for uri, doc := range c.docs {
+		docs[uri] = *doc
+	}
+	return docs
+}
+
+// Raw implements proxy.Server.
+func (s *Client) Raw(ctx context.Context, params *proxy.RawParams) (json.RawMessage, error) {
+	var p interface{}
+	if len(params.Params) > 0 {
+		p = params.Params
+	}
+	if params.Notification {
+		return nil, s.rpc.Notify(ctx, params.Method, p)
+	}
+	var result json.RawMessage
+	if err := s.rpc.Call(ctx, params.Method, p, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
@@ -3,6 +3,7 @@ package acmelsp
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -616,3 +617,23 @@ func TestClientProvidesCodeAction(t *testing.T) {
 		}
 	}
 }
+
+func TestShowMessage(t *testing.T) {
+	ss, _ := newRecordServerSet(t, &nopDiagnosticsWriter{})
+	var buf bytes.Buffer
+	ss.MessageWriter = &buf
+	h := &clientHandler{
+		cfg: ss.ClientConfig(ss.Data[0]),
+	}
+	err := h.ShowMessage(context.Background(), &protocol.ShowMessageParams{
+		Type:    protocol.Warning,
+		Message: "go.mod is not tidy",
+	})
+	if err != nil {
+		t.Fatalf("ShowMessage failed: %v", err)
+	}
+	want := fmt.Sprintf("%v: go.mod is not tidy\n", protocol.Warning)
+	if got := buf.String(); got != want {
+		t.Errorf("message window got %q; want %q", got, want)
+	}
+}
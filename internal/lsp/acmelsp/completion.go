@@ -0,0 +1,419 @@
+package acmelsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tw4452852/acme-lsp/internal/acmeutil"
+	"github.com/tw4452852/acme-lsp/internal/lsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+const completionWinName = "/LSP/Completion"
+
+// maxResolvedCompletionItems is the maximum number of completion
+// candidates resolved (e.g. for documentation) when listing them.
+const maxResolvedCompletionItems = 10
+
+// CompletionWindow shows the completion candidates at the cursor position
+// in an acme window. Executing (middle-clicking) a candidate applies it to
+// the window where completion was requested and closes the candidates window.
+func (rc *RemoteCmd) CompletionWindow(ctx context.Context) error {
+	w, err := acmeutil.OpenWin(rc.winid)
+	if err != nil {
+		return err
+	}
+	defer w.CloseFiles()
+
+	pos, body, items, err := rc.windowCompletionItems(ctx, w)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Fprintf(rc.Stderr, "no completion\n")
+		return nil
+	}
+	rc.resolveCompletionItems(ctx, pos.TextDocument, items, maxResolvedCompletionItems)
+
+	cw, err := newCompletionWin(items)
+	if err != nil {
+		return fmt.Errorf("failed to create completion window: %v", err)
+	}
+	defer cw.Close()
+
+	item := cw.Select()
+	if item == nil {
+		return nil
+	}
+
+	// The window may have been edited while the candidates were shown,
+	// which makes the edits of the candidate stale. If so, completion is
+	// requested again and the candidate with the same label is applied.
+	pos1, body1, err := windowState(w)
+	if err != nil {
+		return err
+	}
+	if *pos1 != *pos || !bytes.Equal(body1, body) {
+		if err := rc.DidChange(ctx); err != nil {
+			return err
+		}
+		pos, _, items, err = rc.windowCompletionItems(ctx, w)
+		if err != nil {
+			return err
+		}
+		label := item.Label
+		item = findCompletionItem(items, label)
+		if item == nil {
+			return fmt.Errorf("window changed and %q is no longer a completion candidate", label)
+		}
+	}
+	selected := []protocol.CompletionItem{*item}
+	rc.resolveCompletionItems(ctx, pos.TextDocument, selected, 1)
+	return rc.applyCompletionItem(ctx, w, &selected[0])
+}
+
+// windowCompletionItems returns the cursor position and body of window w,
+// and the completion candidates at the cursor which match the text being
+// completed.
+func (rc *RemoteCmd) windowCompletionItems(ctx context.Context, w *acmeutil.Win) (*protocol.TextDocumentPositionParams, []byte, []protocol.CompletionItem, error) {
+	pos, body, err := windowState(w)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	result, err := rc.server.Completion(ctx, &protocol.CompletionParams{
+		Context: &protocol.CompletionContext{
+			TriggerKind: protocol.Invoked,
+		},
+		TextDocumentPositionParams: *pos,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	items, err := matchingCompletionItems(w, pos.Position, result.Items, rc.CompletionMatcher)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pos, body, items, nil
+}
+
+// windowState returns the cursor position and body of window w.
+func windowState(w *acmeutil.Win) (*protocol.TextDocumentPositionParams, []byte, error) {
+	pos, _, err := text.Position(w)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := w.ReadAll("body")
+	if err != nil {
+		return nil, nil, err
+	}
+	return pos, body, nil
+}
+
+// findCompletionItem returns the first completion item
+// with the given label, or nil if there is none.
+func findCompletionItem(items []protocol.CompletionItem, label string) *protocol.CompletionItem {
+	for i := range items {
+		if items[i].Label == label {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// resolveCompletionItems replaces the first n items with the result of
+// completionItem/resolve request, if the server supports it.
+func (rc *RemoteCmd) resolveCompletionItems(ctx context.Context, doc protocol.TextDocumentIdentifier, items []protocol.CompletionItem, n int) {
+	ir, err := rc.server.InitializeResult(ctx, &doc)
+	if err != nil {
+		dprintf("InitializeResult failed: %v\n", err)
+		return
+	}
+	if cp := ir.Capabilities.CompletionProvider; cp == nil || !cp.ResolveProvider {
+		return
+	}
+	if n > len(items) {
+		n = len(items)
+	}
+	for i := 0; i < n; i++ {
+		item, err := rc.server.ResolveCompletionItemOnDocument(ctx, &proxy.ResolveCompletionItemOnDocumentParams{
+			TextDocument:   doc,
+			CompletionItem: items[i],
+		})
+		if err != nil {
+			dprintf("completion item resolve failed: %v\n", err)
+			continue
+		}
+		if item.TextEdit == nil {
+			// Keep the edit we made up for the unresolved item.
+			item.TextEdit = items[i].TextEdit
+		}
+		items[i] = *item
+	}
+}
+
+// matchingCompletionItems returns the completion items at position pos
+// which match the text being completed according to matcher.
+func matchingCompletionItems(w *acmeutil.Win, pos protocol.Position, items []protocol.CompletionItem, matcher string) ([]protocol.CompletionItem, error) {
+	col := int(pos.Character)
+	buf := make([]byte, col*utf8.UTFMax)
+	n, err := w.ReadAt(int(pos.Line), 0, int(pos.Line), col, buf)
+	if err != nil {
+		return nil, err
+	}
+	return filterCompletionItems(items, string(buf[:n]), pos, matcher), nil
+}
+
+// filterCompletionItems returns the completion items which match the text
+// being completed according to matcher, ordered by the server's ranking.
+// The line is the text before the cursor position pos. Items without a
+// TextEdit are given one which replaces the identifier before the cursor.
+func filterCompletionItems(items []protocol.CompletionItem, line string, pos protocol.Position, matcher string) []protocol.CompletionItem {
+	runes := []rune(line)
+	word := len(runes)
+	for word > 0 && isIdentifier(runes[word-1]) {
+		word--
+	}
+
+	var matches []protocol.CompletionItem
+	for _, item := range items {
+		if item.TextEdit == nil {
+			newText := item.InsertText
+			if newText == "" {
+				newText = item.Label
+			}
+			item.TextEdit = &protocol.TextEdit{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: pos.Line, Character: float64(word)},
+					End:   pos,
+				},
+				NewText: newText,
+			}
+		}
+		prefix := string(runes[word:])
+		if start := item.TextEdit.Range.Start; start.Line == pos.Line && int(start.Character) <= len(runes) {
+			prefix = string(runes[int(start.Character):])
+		}
+		filter := item.FilterText
+		if filter == "" {
+			filter = item.Label
+		}
+		if matchCompletion(matcher, filter, prefix) {
+			matches = append(matches, item)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := &matches[i], &matches[j]
+		if a.Preselect != b.Preselect {
+			return a.Preselect
+		}
+		return completionSortText(a) < completionSortText(b)
+	})
+	return matches
+}
+
+func completionSortText(item *protocol.CompletionItem) string {
+	if item.SortText != "" {
+		return item.SortText
+	}
+	return item.Label
+}
+
+// matchCompletion reports whether the candidate s matches the text
+// being completed according to matcher.
+func matchCompletion(matcher, s, prefix string) bool {
+	switch matcher {
+	case config.CaseSensitiveMatcher:
+		return strings.HasPrefix(s, prefix)
+	case config.FuzzyMatcher:
+		s = strings.ToLower(s)
+		for _, r := range strings.ToLower(prefix) {
+			i := strings.IndexRune(s, r)
+			if i < 0 {
+				return false
+			}
+			s = s[i+utf8.RuneLen(r):]
+		}
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
+
+// applyCompletionItem applies the TextEdit and AdditionalTextEdits of the
+// completion item to window w. If the item is a snippet, its first tab stop
+// is selected and the acme-lsp server remembers the rest for NextTabStop.
+func (rc *RemoteCmd) applyCompletionItem(ctx context.Context, w *acmeutil.Win, item *protocol.CompletionItem) error {
+	if item.TextEdit == nil {
+		return fmt.Errorf("nil TextEdit in completion item")
+	}
+	te := *item.TextEdit
+	var snip *text.Snippet
+	if item.InsertTextFormat == protocol.SnippetTextFormat {
+		snip = text.ParseSnippet(te.NewText)
+		te.NewText = snip.Text
+	}
+	edits := append([]protocol.TextEdit{}, item.AdditionalTextEdits...)
+	edits = append(edits, te)
+	q0, err := text.EditOffset(w, edits, len(edits)-1)
+	if err != nil {
+		return fmt.Errorf("failed to apply completion edit: %v", err)
+	}
+
+	var stops []text.TabStop
+	if snip != nil {
+		for _, ts := range snip.TabStops {
+			stops = append(stops, text.TabStop{Q0: q0 + ts.Q0, Q1: q0 + ts.Q1})
+		}
+	}
+	if len(stops) > 0 {
+		if err := selectTabStop(w, stops[0]); err != nil {
+			return fmt.Errorf("failed to select tab stop: %v", err)
+		}
+	}
+	return rc.server.SetTabStops(ctx, &proxy.TabStopsParams{
+		WinID:    rc.winid,
+		TabStops: stops,
+	})
+}
+
+// NextTabStop selects the next tab stop of the snippet
+// last inserted into the window.
+func (rc *RemoteCmd) NextTabStop(ctx context.Context) error {
+	w, err := acmeutil.OpenWin(rc.winid)
+	if err != nil {
+		return err
+	}
+	defer w.CloseFiles()
+
+	q0, q1, err := w.CurrentAddr()
+	if err != nil {
+		return err
+	}
+	ts, err := rc.server.NextTabStop(ctx, &proxy.NextTabStopParams{
+		WinID: rc.winid,
+		Q0:    q0,
+		Q1:    q1,
+	})
+	if err != nil {
+		return err
+	}
+	return selectTabStop(w, *ts)
+}
+
+func selectTabStop(w *acmeutil.Win, ts text.TabStop) error {
+	if err := w.Addr("#%d,#%d", ts.Q0, ts.Q1); err != nil {
+		return err
+	}
+	if err := w.Ctl("dot=addr"); err != nil {
+		return err
+	}
+	return w.Ctl("show")
+}
+
+// completionWin is an acme window listing completion candidates,
+// one per line.
+type completionWin struct {
+	*acmeutil.Win
+	items []protocol.CompletionItem
+	lines []int // rune offset where the line of each item starts
+}
+
+func newCompletionWin(items []protocol.CompletionItem) (*completionWin, error) {
+	// Take over from a previous completion window. The L command
+	// waiting on it will exit once the window is deleted.
+	if old, err := acmeutil.Hijack(completionWinName); err == nil {
+		old.Del(true)
+		old.CloseFiles()
+	}
+	w, err := acmeutil.NewWin()
+	if err != nil {
+		return nil, err
+	}
+	w.Name(completionWinName)
+
+	var buf bytes.Buffer
+	lines := make([]int, len(items))
+	q := 0
+	for i, item := range items {
+		lines[i] = q
+		line := formatCompletionItem(&item)
+		buf.WriteString(line)
+		q += utf8.RuneCountInString(line)
+	}
+	if _, err := w.Write("body", buf.Bytes()); err != nil {
+		w.Del(true)
+		w.CloseFiles()
+		return nil, err
+	}
+	w.Addr("#0")
+	w.Ctl("dot=addr")
+	w.Ctl("show")
+	w.Ctl("clean")
+	return &completionWin{
+		Win:   w,
+		items: items,
+		lines: lines,
+	}, nil
+}
+
+// formatCompletionItem returns a line containing the label, kind and
+// detail of a completion item, followed by its documentation indented.
+func formatCompletionItem(item *protocol.CompletionItem) string {
+	s := item.Label
+	if item.Kind != 0 {
+		s += fmt.Sprintf("\t%v", item.Kind)
+	}
+	if item.Detail != "" {
+		s += "\t" + item.Detail
+	}
+	s = strings.Replace(s, "\n", " ", -1) + "\n"
+	if doc := strings.TrimSpace(lsp.MarkupText(item.Documentation)); doc != "" {
+		s += "\t" + strings.Replace(doc, "\n", "\n\t", -1) + "\n"
+	}
+	return s
+}
+
+// itemAt returns the completion item shown at rune offset q.
+func (cw *completionWin) itemAt(q int) *protocol.CompletionItem {
+	i := sort.Search(len(cw.lines), func(i int) bool {
+		return cw.lines[i] > q
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+	return &cw.items[i]
+}
+
+// Select waits until a completion item is executed within the window
+// body and returns it. It returns nil if the window is deleted.
+func (cw *completionWin) Select() *protocol.CompletionItem {
+	for ev := range cw.EventChan() {
+		if ev == nil {
+			break
+		}
+		switch ev.C2 {
+		case 'x', 'X': // execute
+			if string(ev.Text) == "Del" {
+				return nil
+			}
+			if ev.C2 == 'X' { // in body
+				if item := cw.itemAt(ev.Q0); item != nil {
+					return item
+				}
+			}
+		}
+		cw.WriteEvent(ev)
+	}
+	return nil
+}
+
+func (cw *completionWin) Close() {
+	cw.Del(true)
+	cw.CloseFiles()
+}
@@ -0,0 +1,140 @@
+package acmelsp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func TestCompletionWinItemAt(t *testing.T) {
+	items := []protocol.CompletionItem{
+		{Label: "Println", Kind: protocol.FunctionCompletion, Detail: "func(a ...interface{}) (n int, err error)"},
+		{Label: "Printf", Kind: protocol.FunctionCompletion},
+	}
+	cw := &completionWin{
+		items: items,
+		lines: []int{0, 60},
+	}
+	for _, tc := range []struct {
+		q    int
+		want string
+	}{
+		{0, "Println"},
+		{59, "Println"},
+		{60, "Printf"},
+		{100, "Printf"},
+	} {
+		got := cw.itemAt(tc.q)
+		if got == nil || got.Label != tc.want {
+			t.Errorf("item at %v is %v; want %v", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestFormatCompletionItem(t *testing.T) {
+	for _, tc := range []struct {
+		item protocol.CompletionItem
+		want string
+	}{
+		{
+			protocol.CompletionItem{Label: "Printf", Kind: protocol.FunctionCompletion, Detail: "func(format string, a ...interface{})"},
+			"Printf\tfunc\tfunc(format string, a ...interface{})\n",
+		},
+		{
+			protocol.CompletionItem{Label: "fmt", Kind: protocol.ModuleCompletion},
+			"fmt\tpackage\n",
+		},
+		{
+			protocol.CompletionItem{Label: "x"},
+			"x\n",
+		},
+		{
+			protocol.CompletionItem{
+				Label:  "Println",
+				Kind:   protocol.FunctionCompletion,
+				Detail: "func(a ...interface{}) (n int, err error)",
+				Documentation: &protocol.MarkupContent{
+					Kind:  protocol.PlainText,
+					Value: "Println formats using the default formats.\nSpaces are always added between operands.\n",
+				},
+			},
+			"Println\tfunc\tfunc(a ...interface{}) (n int, err error)\n" +
+				"\tPrintln formats using the default formats.\n" +
+				"\tSpaces are always added between operands.\n",
+		},
+	} {
+		got := formatCompletionItem(&tc.item)
+		if got != tc.want {
+			t.Errorf("formatCompletionItem(%v) is %q; want %q", tc.item.Label, got, tc.want)
+		}
+	}
+}
+
+func TestMatchCompletion(t *testing.T) {
+	for _, tc := range []struct {
+		matcher, s, prefix string
+		want               bool
+	}{
+		{config.CaseInsensitiveMatcher, "Println", "print", true},
+		{config.CaseInsensitiveMatcher, "Println", "pln", false},
+		{config.CaseSensitiveMatcher, "Println", "print", false},
+		{config.CaseSensitiveMatcher, "Println", "Print", true},
+		{config.FuzzyMatcher, "Println", "pln", true},
+		{config.FuzzyMatcher, "Println", "plx", false},
+		{config.FuzzyMatcher, "Println", "", true},
+	} {
+		got := matchCompletion(tc.matcher, tc.s, tc.prefix)
+		if got != tc.want {
+			t.Errorf("%v matcher: match(%q, %q) is %v; want %v", tc.matcher, tc.s, tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestFilterCompletionItems(t *testing.T) {
+	pos := protocol.Position{Line: 3, Character: 7}
+	items := []protocol.CompletionItem{
+		{Label: "Sprint", SortText: "3"},
+		{Label: "Println", SortText: "2", InsertText: "Println()"},
+		{Label: "Errorf", SortText: "0"},
+		{Label: "Printf", SortText: "1"},
+		{
+			Label:     "Print",
+			SortText:  "4",
+			Preselect: true,
+			TextEdit: &protocol.TextEdit{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 3, Character: 1},
+					End:   pos,
+				},
+				NewText: "fmt.Print",
+			},
+			FilterText: "fmt.Print",
+		},
+	}
+	got := filterCompletionItems(items, "\tfmt.Pr", pos, config.CaseInsensitiveMatcher)
+
+	var labels []string
+	for _, item := range got {
+		labels = append(labels, item.Label)
+	}
+	want := []string{"Print", "Printf", "Println"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("matching items are %q; want %q", labels, want)
+	}
+	te := got[2].TextEdit
+	wantEdit := &protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 3, Character: 5},
+			End:   pos,
+		},
+		NewText: "Println()",
+	}
+	if !reflect.DeepEqual(te, wantEdit) {
+		t.Errorf("synthesized edit is %+v; want %+v", te, wantEdit)
+	}
+	if got[1].TextEdit.NewText != "Printf" {
+		t.Errorf("synthesized edit text is %q; want label %q", got[1].TextEdit.NewText, "Printf")
+	}
+}
@@ -28,12 +28,32 @@ const (
 	ProxyFlags
 )
 
+// Algorithms that can be used for File.CompletionMatcher.
+const (
+	CaseInsensitiveMatcher = "CaseInsensitive"
+	CaseSensitiveMatcher   = "CaseSensitive"
+	FuzzyMatcher           = "Fuzzy"
+)
+
+// Methods that can be used for File.LocationOpener.
+const (
+	AutoOpener    = "Auto"
+	PlumberOpener = "Plumber"
+	AcmeOpener    = "Acme"
+)
+
 // File represents user configuration file for acme-lsp and L.
 type File struct {
 	// Network and address used for communication between acme-lsp and L.
 	// Only required on systems without unix domain socket.
 	ProxyNetwork, ProxyAddress string
 
+	// Network and address where acme-lsp listens for connections from
+	// LSP clients (e.g. other editors or scripts), which then share the
+	// LSP servers with acme. Requests are forwarded to the server that
+	// handles the document URI. Disabled if LSPAddress is empty.
+	LSPNetwork, LSPAddress string
+
 	// Network and address where acme is serving 9P file server.
 	// Only required on systems without unix domain socket.
 	AcmeNetwork, AcmeAddress string
@@ -56,6 +76,27 @@ type File struct {
 	// LSP code actions to run when Put is executed in a window.
 	CodeActionsOnPut []protocol.CodeActionKind
 
+	// Algorithm used to filter completion candidates by the text being
+	// completed. One of "CaseInsensitive" (prefix match ignoring case),
+	// "CaseSensitive" (prefix match), or "Fuzzy" (the text is a subsequence
+	// of the candidate, ignoring case).
+	CompletionMatcher string
+
+	// How locations (e.g. the result of L def) are opened in acme.
+	// One of "Plumber" (send them to the plumber), "Acme" (open them
+	// using acme's file server directly), or "Auto" (use the plumber
+	// if it's running, otherwise acme).
+	LocationOpener string
+
+	// Show the output of L hov, impls, refs, and syms in acme windows
+	// named after the command (e.g. /LSP/refs) instead of printing it.
+	ResultWindows bool
+
+	// Show messages sent by the LSP servers (window/showMessage), e.g.
+	// while executing L exec, in the /LSP/Messages acme window instead
+	// of logging them.
+	MessageWindow bool
+
 	// LSP servers keyed by a user provided name.
 	Servers map[string]*Server
 
@@ -126,6 +167,7 @@ func Default() *Config {
 		File: File{
 			ProxyNetwork:         "unix",
 			ProxyAddress:         filepath.Join(client.Namespace(), "acme-lsp.rpc"),
+			LSPNetwork:           "unix",
 			AcmeNetwork:          "unix",
 			AcmeAddress:          filepath.Join(client.Namespace(), "acme"),
 			WorkspaceDirectories: nil,
@@ -134,8 +176,10 @@ func Default() *Config {
 			CodeActionsOnPut: []protocol.CodeActionKind{
 				protocol.SourceOrganizeImports,
 			},
-			Servers:          nil,
-			FilenameHandlers: nil,
+			CompletionMatcher: CaseInsensitiveMatcher,
+			LocationOpener:    AutoOpener,
+			Servers:           nil,
+			FilenameHandlers:  nil,
 		},
 	}
 }
@@ -177,6 +221,9 @@ func Load() (*Config, error) {
 	if cfg.File.ProxyAddress == "" {
 		cfg.File.ProxyAddress = def.File.ProxyAddress
 	}
+	if cfg.File.LSPNetwork == "" {
+		cfg.File.LSPNetwork = def.File.LSPNetwork
+	}
 	if cfg.File.AcmeNetwork == "" {
 		cfg.File.AcmeNetwork = def.File.AcmeNetwork
 	}
@@ -186,6 +233,20 @@ func Load() (*Config, error) {
 	if cfg.File.RootDirectory == "" {
 		cfg.File.RootDirectory = def.File.RootDirectory
 	}
+	switch cfg.File.CompletionMatcher {
+	case "":
+		cfg.File.CompletionMatcher = def.File.CompletionMatcher
+	case CaseInsensitiveMatcher, CaseSensitiveMatcher, FuzzyMatcher:
+	default:
+		return nil, fmt.Errorf("unknown completion matcher %q", cfg.File.CompletionMatcher)
+	}
+	switch cfg.File.LocationOpener {
+	case "":
+		cfg.File.LocationOpener = def.File.LocationOpener
+	case AutoOpener, PlumberOpener, AcmeOpener:
+	default:
+		return nil, fmt.Errorf("unknown location opener %q", cfg.File.LocationOpener)
+	}
 	cacheDir, err := os.UserCacheDir()
 	if err != nil {
 		return nil, err
@@ -253,6 +314,12 @@ func (cfg *Config) ParseFlags(flags Flags, f *flag.FlagSet, arguments []string)
 			"network used for communication between acme-lsp and L")
 		f.StringVar(&cfg.ProxyAddress, "proxy.addr", cfg.ProxyAddress,
 			"address used for communication between acme-lsp and L")
+		if flags&LangServerFlags != 0 {
+			f.StringVar(&cfg.LSPNetwork, "lsp.net", cfg.LSPNetwork,
+				"network where acme-lsp listens for LSP clients")
+			f.StringVar(&cfg.LSPAddress, "lsp.addr", cfg.LSPAddress,
+				"address where acme-lsp listens for LSP clients (disabled if empty)")
+		}
 	}
 	if flags&LangServerFlags != 0 {
 		f.BoolVar(&cfg.Verbose, "debug", cfg.Verbose, "turn on debugging prints (deprecated: use -v)")
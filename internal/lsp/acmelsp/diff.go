@@ -0,0 +1,209 @@
+package acmelsp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+// in a unified diff.
+const diffContext = 3
+
+// diffOp is one line of a line-based diff: the line a of the old text
+// is kept (' ') or deleted ('-'), or the line b of the new text is
+// inserted ('+'). Both a and b are zero-based and give the position in
+// the respective text where the operation applies.
+type diffOp struct {
+	kind byte
+	a, b int
+}
+
+// printDiff writes the changes to the named file from old to new text
+// to w as a unified diff. Nothing is written if the texts are equal.
+func printDiff(w io.Writer, name string, old, new []byte) error {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- %v.orig\n+++ %v\n", name, name)
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				if end += diffContext; end > next {
+					end = next
+				}
+				break
+			}
+			end = next
+		}
+		printHunk(bw, a, b, ops[start:end])
+		i = end
+	}
+	return bw.Flush()
+}
+
+// printHunk writes the operations as one hunk of a unified diff.
+func printHunk(w io.Writer, a, b []string, ops []diffOp) {
+	var na, nb int
+	for _, op := range ops {
+		if op.kind != '+' {
+			na++
+		}
+		if op.kind != '-' {
+			nb++
+		}
+	}
+	fmt.Fprintf(w, "@@ -%v +%v @@\n", hunkRange(ops[0].a, na), hunkRange(ops[0].b, nb))
+	for _, op := range ops {
+		var line string
+		if op.kind == '+' {
+			line = b[op.b]
+		} else {
+			line = a[op.a]
+		}
+		fmt.Fprintf(w, "%c%v", op.kind, line)
+		if len(line) == 0 || line[len(line)-1] != '\n' {
+			fmt.Fprintf(w, "\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the range of n lines starting at the zero-based
+// line start the way diff -u does.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%v,0", start)
+	case 1:
+		return fmt.Sprintf("%v", start+1)
+	}
+	return fmt.Sprintf("%v,%v", start+1, n)
+}
+
+// splitLines splits the text into lines, each including its newline.
+func splitLines(b []byte) []string {
+	var lines []string
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n') + 1
+		if i == 0 {
+			i = len(b)
+		}
+		lines = append(lines, string(b[:i]))
+		b = b[i:]
+	}
+	return lines
+}
+
+// diffLines returns the shortest sequence of operations that turns
+// the lines a into the lines b, using Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var pk int
+		if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := v[max+pk]
+		py := px - pk
+		for x > px && y > py {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', x, y})
+		}
+		if d > 0 {
+			if x == px {
+				y--
+				ops = append(ops, diffOp{'+', x, y})
+			} else {
+				x--
+				ops = append(ops, diffOp{'-', x, y})
+			}
+		}
+		x, y = px, py
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	// Like diff, list the deleted lines of each change before the
+	// inserted ones.
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		x, y := ops[i].a, ops[i].b
+		sort.SliceStable(ops[i:j], func(k, l int) bool {
+			return ops[i+k].kind == '-' && ops[i+l].kind == '+'
+		})
+		for k := i; k < j; k++ {
+			ops[k].a, ops[k].b = x, y
+			if ops[k].kind == '-' {
+				x++
+			} else {
+				y++
+			}
+		}
+		i = j
+	}
+	return ops
+}
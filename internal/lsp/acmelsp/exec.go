@@ -12,21 +12,113 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tw4452852/acme-lsp/internal/lsp"
 	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
 	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
 )
 
 type Server struct {
-	conn   net.Conn
 	Client *Client
+
+	mu       sync.Mutex
+	conn     net.Conn    // connection to the server, replaced when it's restarted
+	process  *os.Process // nil if the server was dialed
+	pid      int         // process ID, or 0 if the server was dialed
+	started  time.Time   // when the server was last started
+	restarts int         // number of times the server was restarted
+	exitErr  error       // error returned by the last server process
+	stopped  bool        // server was shut down on purpose and must not be restarted
+
+	// restarted is called with the documents that were open when the
+	// server process died, after it's restarted and initialized.
+	restarted func(docs map[protocol.DocumentURI]document)
+}
+
+// shutdownTimeout is how long a server is given to shut down and exit.
+const shutdownTimeout = 5 * time.Second
+
+// setStarted records that the given server process was started and
+// is connected to through conn. The process is nil if the server was dialed.
+func (s *Server) setStarted(p *os.Process, conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn = conn
+	s.process = p
+	s.pid = 0
+	if p != nil {
+		s.pid = p.Pid
+	}
+	s.started = time.Now()
+}
+
+// shutdown asks the server to shut down and exit, and closes the
+// connection to it. A server process which doesn't exit in time
+// is killed. The process isn't restarted. A dialed server may be
+// used by other clients, so it's only disconnected from.
+func (s *Server) shutdown(ctx context.Context) {
+	s.mu.Lock()
+	s.stopped = true
+	p := s.process
+	s.mu.Unlock()
+
+	if p == nil {
+		s.Close()
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+	if err := s.Client.Shutdown(ctx); err != nil {
+		log.Printf("language server shutdown failed: %v", err)
+	} else if err := s.Client.Exit(ctx); err != nil {
+		log.Printf("language server exit failed: %v", err)
+	}
+	s.Close()
+	// Kill fails harmlessly if the process has already exited.
+	time.AfterFunc(shutdownTimeout, func() { p.Kill() })
+}
+
+// setRestarted sets the function called after the server process
+// is restarted (see Server.restarted).
+func (s *Server) setRestarted(f func(docs map[protocol.DocumentURI]document)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restarted = f
+}
+
+// history returns the number of times the server was restarted
+// and the error returned by the last server process.
+func (s *Server) history() (restarts int, exitErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.restarts, s.exitErr
+}
+
+// inherit adds the restart history of a previous server instance
+// (see history) to the server's.
+func (s *Server) inherit(restarts int, exitErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restarts += restarts
+	if s.exitErr == nil {
+		s.exitErr = exitErr
+	}
 }
 
 func (s *Server) Close() {
 	if s != nil {
-		s.conn.Close()
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+		conn.Close()
 	}
 }
 
@@ -60,33 +152,51 @@ func execServer(cs *config.Server, cfg *ClientConfig) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	srv := &Server{
-		conn: p1,
-	}
+	srv := &Server{}
+	srv.setStarted(cmd.Process, p1)
 
 	// Restart server if it dies.
 	go func() {
 		for {
 			err := cmd.Wait()
+			srv.mu.Lock()
+			stopped := srv.stopped
+			srv.exitErr = err
+			if !stopped {
+				srv.restarts++
+			}
+			srv.mu.Unlock()
+			if stopped {
+				return
+			}
 			log.Printf("language server %v exited: %v; restarting...", args[0], err)
 
 			// TODO(fhs): cancel using context?
-			srv.conn.Close()
+			srv.Close()
 
 			cmd, p1, err = startCommand()
 			if err != nil {
 				log.Printf("%v", err)
 				return
 			}
-			srv.conn = p1
+			srv.setStarted(cmd.Process, p1)
 
 			go func() {
 				// Reinitialize existing client instead of creating a new one
-				// because it's still being used.
+				// because it's still being used. Initializing forgets the
+				// open documents, so they're reopened afterwards.
+				docs := srv.Client.documents()
 				if err := srv.Client.init(p1, cfg); err != nil {
 					log.Printf("initialize after server restart failed: %v", err)
 					cmd.Process.Kill()
-					srv.conn.Close()
+					srv.Close()
+					return
+				}
+				srv.mu.Lock()
+				restarted := srv.restarted
+				srv.mu.Unlock()
+				if restarted != nil {
+					restarted(docs)
 				}
 			}()
 		}
@@ -109,10 +219,11 @@ func dialServer(cs *config.Server, cfg *ClientConfig) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to language server at %v: %v", cs.Address, err)
 	}
-	return &Server{
-		conn:   conn,
+	srv := &Server{
 		Client: c,
-	}, nil
+	}
+	srv.setStarted(nil, conn)
+	return srv, nil
 }
 
 // ServerInfo holds information about a LSP server and optionally a connection to it.
@@ -123,33 +234,176 @@ type ServerInfo struct {
 	Re     *regexp.Regexp // filename regular expression
 	Logger *log.Logger    // Logger for config.Server.LogFile
 	srv    *Server        // running server instance
+
+	// Closed once the server being started is running (or failed to
+	// start), or nil if the server isn't being started.
+	starting chan struct{}
+
+	// Documents that were open in the server when it was stopped.
+	// They're reopened when the server is started again.
+	docs map[protocol.DocumentURI]document
+
+	// Restart history of the stopped server (see Server.history),
+	// which is carried over to the next server instance.
+	restarts int
+	exitErr  error
+
+	mu sync.Mutex // protects all of the above except the configuration
+}
+
+// wait waits until the server isn't being started.
+// Called with info.mu held, which is released while waiting.
+func (info *ServerInfo) wait() {
+	for info.starting != nil {
+		ch := info.starting
+		info.mu.Unlock()
+		<-ch
+		info.mu.Lock()
+	}
 }
 
 func (info *ServerInfo) start(cfg *ClientConfig) (*Server, error) {
-	if info.srv != nil {
-		return info.srv, nil
+	info.mu.Lock()
+	info.wait()
+	if srv := info.srv; srv != nil {
+		info.mu.Unlock()
+		return srv, nil
 	}
+	starting := make(chan struct{})
+	info.starting = starting
+	docs := info.docs
+	info.docs = nil
+	restarts, exitErr := info.restarts, info.exitErr
+	info.mu.Unlock()
 
+	// Starting and initializing the server may take a while, so it's
+	// done without holding info.mu. Other callers of start wait for it.
+	var (
+		srv *Server
+		err error
+	)
 	if len(info.Address) > 0 {
-		srv, err := dialServer(info.Server, cfg)
-		if err != nil {
-			return nil, err
-		}
-		info.srv = srv
+		srv, err = dialServer(info.Server, cfg)
 	} else {
-		srv, err := execServer(info.Server, cfg)
-		if err != nil {
-			return nil, err
+		srv, err = execServer(info.Server, cfg)
+	}
+	if err == nil {
+		srv.inherit(restarts, exitErr)
+		srv.setRestarted(func(docs map[protocol.DocumentURI]document) {
+			if info.running() != srv {
+				return // stopped while restarting
+			}
+			info.reopen(context.Background(), srv, docs)
+
+			info.mu.Lock()
+			defer info.mu.Unlock()
+			if info.srv != srv {
+				// Stopped while reopening, so the documents may
+				// not have been remembered.
+				info.remember(docs)
+			}
+		})
+		info.reopen(context.Background(), srv, docs)
+	}
+
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	info.starting = nil
+	close(starting)
+	if err != nil {
+		info.docs = docs
+		return nil, err
+	}
+	info.srv = srv
+	return srv, nil
+}
+
+// running returns the running server instance,
+// or nil if the server hasn't been started.
+func (info *ServerInfo) running() *Server {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	return info.srv
+}
+
+// stop shuts down the server, if it's running, and remembers
+// the documents that were open in it and its restart history.
+func (info *ServerInfo) stop(ctx context.Context) {
+	info.mu.Lock()
+	info.wait()
+	srv := info.srv
+	if srv == nil {
+		info.mu.Unlock()
+		return
+	}
+	info.docs = srv.Client.documents()
+	info.restarts, info.exitErr = srv.history()
+	info.srv = nil
+	info.mu.Unlock()
+
+	// Don't hold info.mu while waiting for the server to shut down.
+	srv.shutdown(ctx)
+}
+
+// restart stops the server and starts it again.
+// It counts as a restart in the server's history.
+func (info *ServerInfo) restart(ctx context.Context, cfg *ClientConfig) (*Server, error) {
+	info.stop(ctx)
+	info.mu.Lock()
+	info.restarts++
+	info.mu.Unlock()
+	return info.start(cfg)
+}
+
+// reopen opens the given documents, which were open when the server
+// was stopped or died, in the server srv. A document whose text isn't
+// known is read from its acme window or from disk.
+func (info *ServerInfo) reopen(ctx context.Context, srv *Server, docs map[protocol.DocumentURI]document) {
+	for uri, doc := range docs {
+		name := text.ToPath(uri)
+		body := []byte(doc.text)
+		if doc.stale {
+			b, _, err := readFile(name)
+			if err != nil {
+				log.Printf("could not reopen %v: %v", name, err)
+				continue
+			}
+			body = b
+		}
+		lang := doc.languageID
+		if lang == "" {
+			lang = info.LanguageID
+		}
+		if err := lsp.DidOpen(ctx, srv.Client, name, lang, body); err != nil {
+			log.Printf("could not reopen %v: %v", name, err)
+		}
+	}
+}
+
+// remember adds the documents to the ones reopened when the server
+// is started again. Called with info.mu held.
+func (info *ServerInfo) remember(docs map[protocol.DocumentURI]document) {
+	if info.docs == nil {
+		info.docs = make(map[protocol.DocumentURI]document)
+	}
+	for uri, doc := range docs {
+		if _, ok := info.docs[uri]; !ok {
+			info.docs[uri] = doc
 		}
-		info.srv = srv
 	}
-	return info.srv, nil
 }
 
 // ServerSet holds information about a set of LSP servers and connection to them,
 // which are created on-demand.
 type ServerSet struct {
-	Data       []*ServerInfo
+	Data []*ServerInfo
+
+	// Messages sent by the servers (window/showMessage) are written
+	// here if it's not nil. Otherwise, they're logged.
+	MessageWriter io.Writer
+
 	diagWriter DiagnosticsWriter
 	workspaces map[protocol.DocumentURI]*protocol.WorkspaceFolder // set of workspace folders
 	cfg        *config.Config
@@ -222,6 +476,7 @@ func (ss *ServerSet) ClientConfig(info *ServerInfo) *ClientConfig {
 		HideDiag:        ss.cfg.HideDiagnostics,
 		RPCTrace:        ss.cfg.RPCTrace,
 		DiagWriter:      ss.diagWriter,
+		MessageWriter:   ss.MessageWriter,
 		Workspaces:      ss.Workspaces(),
 		Logger:          info.Logger,
 	}
@@ -249,10 +504,64 @@ func (ss *ServerSet) ServerMatch(ctx context.Context, filename string) (proxy.Se
 
 func (ss *ServerSet) CloseAll() {
 	for _, info := range ss.Data {
-		info.srv.Close()
+		info.running().Close()
 	}
 }
 
+// stopAll shuts down all running servers.
+func (ss *ServerSet) stopAll(ctx context.Context) {
+	for _, info := range ss.Data {
+		info.stop(ctx)
+	}
+}
+
+// match returns the servers configured with the given key (e.g. "gopls")
+// or, if there are none, the server for the named file.
+func (ss *ServerSet) match(name string) ([]*ServerInfo, error) {
+	var infos []*ServerInfo
+	for _, info := range ss.Data {
+		if info.ServerKey == name {
+			infos = append(infos, info)
+		}
+	}
+	if len(infos) > 0 {
+		return infos, nil
+	}
+	if info := ss.MatchFile(name); info != nil {
+		return []*ServerInfo{info}, nil
+	}
+	return nil, fmt.Errorf("no language server for %q", name)
+}
+
+// Restart shuts down the servers given by name (see match), starts
+// them again with the current workspace folders, and reopens the
+// documents that were open in them.
+func (ss *ServerSet) Restart(ctx context.Context, name string) error {
+	infos, err := ss.match(name)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if _, err := info.restart(ctx, ss.ClientConfig(info)); err != nil {
+			return fmt.Errorf("could not start language server %v: %v", info.ServerKey, err)
+		}
+	}
+	return nil
+}
+
+// Stop shuts down the servers given by name (see match). A stopped
+// server is started again when it's needed.
+func (ss *ServerSet) Stop(ctx context.Context, name string) error {
+	infos, err := ss.match(name)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		info.stop(ctx)
+	}
+	return nil
+}
+
 func (ss *ServerSet) PrintTo(w io.Writer) {
 	for _, info := range ss.Data {
 		if len(info.Address) > 0 {
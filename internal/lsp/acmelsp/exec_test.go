@@ -5,14 +5,17 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/tw4452852/acme-lsp/internal/lsp"
 	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -116,3 +119,262 @@ func (dw *mockDiagosticsWriter) WriteDiagnostics(params *protocol.PublishDiagnos
 		fmt.Fprintf(dw, "%v: %v\n", lsp.LocationLink(loc), diag.Message)
 	}
 }
+
+func TestServerSetMatch(t *testing.T) {
+	cfg := &config.Config{
+		File: config.File{
+			Servers: map[string]*config.Server{
+				"gopls": {
+					Command: []string{"gopls"},
+				},
+				"pyls": {
+					Command: []string{"pyls"},
+				},
+			},
+			FilenameHandlers: []config.FilenameHandler{
+				{
+					Pattern:   `\.go$`,
+					ServerKey: "gopls",
+				},
+				{
+					Pattern:   `go\.mod$`,
+					ServerKey: "gopls",
+				},
+				{
+					Pattern:   `\.py$`,
+					ServerKey: "pyls",
+				},
+			},
+		},
+	}
+	ss, err := NewServerSet(cfg, &mockDiagosticsWriter{ioutil.Discard})
+	if err != nil {
+		t.Fatalf("failed to create server set: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"gopls", []string{`\.go$`, `go\.mod$`}},
+		{"pyls", []string{`\.py$`}},
+		{"/home/gopher/go.mod", []string{`go\.mod$`}},
+		{"/home/gopher/main.py", []string{`\.py$`}},
+	} {
+		infos, err := ss.match(tc.name)
+		if err != nil {
+			t.Errorf("match(%q) failed: %v", tc.name, err)
+			continue
+		}
+		var patterns []string
+		for _, info := range infos {
+			patterns = append(patterns, info.Pattern)
+		}
+		if !cmp.Equal(patterns, tc.patterns) {
+			t.Errorf("match(%q) returned patterns %v; want %v", tc.name, patterns, tc.patterns)
+		}
+	}
+	if _, err := ss.match("/home/gopher/README"); err == nil {
+		t.Errorf("match succeeded for file without a server")
+	}
+}
+
+// openServer records the documents opened by the client.
+type openServer struct {
+	protocol.Server
+	opened []protocol.TextDocumentItem
+}
+
+func (s *openServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	s.opened = append(s.opened, params.TextDocument)
+	return nil
+}
+
+func (s *openServer) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	return nil
+}
+
+func TestServerInfoReopen(t *testing.T) {
+	ctx := context.Background()
+	mainGo := "/home/gopher/main.go"
+	utilGo := "/home/gopher/util.go"
+
+	// Track the documents of the old server instance.
+	old := &Client{
+		Server: &openServer{},
+		docs:   make(map[protocol.DocumentURI]*document),
+	}
+	if err := lsp.DidOpen(ctx, old, mainGo, "go", []byte("package main\n")); err != nil {
+		t.Fatalf("DidOpen failed: %v", err)
+	}
+	change := func(name, body string) {
+		err := old.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{
+					URI: text.ToURI(name),
+				},
+			},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{
+				{Text: body},
+			},
+		})
+		if err != nil {
+			t.Fatalf("DidChange failed: %v", err)
+		}
+	}
+	change(mainGo, "package main // unsaved\n")
+	change(utilGo, "package main // not opened\n")
+
+	srv := &openServer{}
+	info := &ServerInfo{
+		FilenameHandler: &config.FilenameHandler{LanguageID: "golang"},
+	}
+	info.reopen(ctx, &Server{
+		Client: &Client{
+			Server: srv,
+			docs:   make(map[protocol.DocumentURI]*document),
+		},
+	}, old.documents())
+
+	got := make(map[protocol.DocumentURI]protocol.TextDocumentItem)
+	for _, doc := range srv.opened {
+		got[doc.URI] = doc
+	}
+	want := map[protocol.DocumentURI]protocol.TextDocumentItem{
+		text.ToURI(mainGo): {
+			URI:        text.ToURI(mainGo),
+			LanguageID: "go",
+			Text:       "package main // unsaved\n",
+		},
+		text.ToURI(utilGo): {
+			URI:        text.ToURI(utilGo),
+			LanguageID: "golang",
+			Text:       "package main // not opened\n",
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("reopened documents are %v; want %v", got, want)
+	}
+}
+
+// exitServer records whether the client asked it to shut down and exit.
+type exitServer struct {
+	protocol.Server
+	shutdown, exit bool
+}
+
+func (s *exitServer) Shutdown(ctx context.Context) error {
+	s.shutdown = true
+	return nil
+}
+
+func (s *exitServer) Exit(ctx context.Context) error {
+	s.exit = true
+	return nil
+}
+
+func TestServerInfoStopDialed(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	uri := protocol.DocumentURI("file:///home/gopher/main.go")
+	srv := &exitServer{}
+	info := &ServerInfo{
+		Server: &config.Server{Address: "localhost:4389"},
+		srv: &Server{
+			Client: &Client{
+				Server: srv,
+				docs: map[protocol.DocumentURI]*document{
+					uri: {languageID: "go", text: "package main\n"},
+				},
+			},
+		},
+	}
+	info.srv.setStarted(nil, c1)
+	info.stop(context.Background())
+
+	if srv.shutdown || srv.exit {
+		t.Errorf("dialed server was asked to shut down (%v) or exit (%v)", srv.shutdown, srv.exit)
+	}
+	if _, err := c2.Write([]byte("{}")); err == nil {
+		t.Errorf("connection to dialed server is still open")
+	}
+	if info.running() != nil {
+		t.Errorf("server is running after stop")
+	}
+	want := document{languageID: "go", text: "package main\n"}
+	if len(info.docs) != 1 || info.docs[uri] != want {
+		t.Errorf("documents to reopen are %v; want %v: %v", info.docs, uri, want)
+	}
+}
+
+// initServer is a language server which can only be initialized.
+type initServer struct {
+	protocol.Server
+}
+
+func (s *initServer) Initialize(ctx context.Context, params *protocol.ParamInitia) (*protocol.InitializeResult, error) {
+	return &protocol.InitializeResult{}, nil
+}
+
+func (s *initServer) Initialized(ctx context.Context, params *protocol.InitializedParams) error {
+	return nil
+}
+
+func TestServerSetRestartHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, rpc, _ := protocol.NewServer(ctx, jsonrpc2.NewHeaderStream(conn, conn), &initServer{})
+			go rpc.Run(ctx)
+		}
+	}()
+
+	cfg := &config.Config{
+		File: config.File{
+			Servers: map[string]*config.Server{
+				"gopls": {
+					Address: ln.Addr().String(),
+				},
+			},
+			FilenameHandlers: []config.FilenameHandler{
+				{
+					Pattern:   `\.go$`,
+					ServerKey: "gopls",
+				},
+			},
+		},
+	}
+	ss, err := NewServerSet(cfg, &nopDiagnosticsWriter{})
+	if err != nil {
+		t.Fatalf("NewServerSet failed: %v", err)
+	}
+	defer ss.stopAll(ctx)
+
+	srv, _, err := ss.StartForFile("main.go")
+	if err != nil {
+		t.Fatalf("StartForFile failed: %v", err)
+	}
+	exitErr := fmt.Errorf("exit status 2")
+	srv.inherit(1, exitErr)
+
+	if err := ss.Restart(ctx, "gopls"); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	st := ss.status().Servers[0]
+	if !st.Running || st.Restarts != 2 || st.ExitError != exitErr.Error() {
+		t.Errorf("status after restart is running %v, %v restarts, exit error %q; want true, 2, %q",
+			st.Running, st.Restarts, st.ExitError, exitErr)
+	}
+}
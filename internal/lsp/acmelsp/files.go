@@ -25,6 +25,10 @@ type FileManager struct {
 	wins map[string]struct{} // set of open files
 	mu   sync.Mutex
 
+	// Clients are the LSP clients connected to acme-lsp (see
+	// ListenAndServeLSP), which may also have the files open. It may be nil.
+	Clients *LSPClientSet
+
 	cfg *config.Config
 }
 
@@ -91,6 +95,15 @@ func (fm *FileManager) Run() {
 	}
 }
 
+// isOpen returns true if the named file is open in acme.
+func (fm *FileManager) isOpen(name string) bool {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	_, ok := fm.wins[name]
+	return ok
+}
+
 func (fm *FileManager) withClient(winid int, name string, f func(*Client, *acmeutil.Win) error) error {
 	s, found, err := fm.ss.StartForFile(name)
 	if err != nil {
@@ -114,22 +127,34 @@ func (fm *FileManager) withClient(winid int, name string, f func(*Client, *acmeu
 
 func (fm *FileManager) didOpen(winid int, name string) error {
 	return fm.withClient(winid, name, func(c *Client, w *acmeutil.Win) error {
-		fm.mu.Lock()
-		defer fm.mu.Unlock()
-
-		if _, ok := fm.wins[name]; ok {
-			return fmt.Errorf("file already open in file manager: %v", name)
-		}
-		fm.wins[name] = struct{}{}
-
 		b, err := w.ReadAll("body")
 		if err != nil {
 			return err
 		}
-		return lsp.DidOpen(context.Background(), c, name, c.cfg.FilenameHandler.LanguageID, b)
+		return fm.open(context.Background(), c, name, b)
 	})
 }
 
+// open tells the server that the named file was opened in acme with
+// the given text. If a LSP client already opened the file, the text
+// is sent as a change instead.
+func (fm *FileManager) open(ctx context.Context, c *Client, name string, body []byte) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if _, ok := fm.wins[name]; ok {
+		return fmt.Errorf("file already open in file manager: %v", name)
+	}
+	fm.wins[name] = struct{}{}
+
+	if fm.Clients.isOpen(text.ToURI(name)) {
+		return lsp.DidChange(ctx, c, name, body)
+	}
+	return lsp.DidOpen(ctx, c, name, c.cfg.FilenameHandler.LanguageID, body)
+}
+
+// didClose tells the server that the named file was closed in acme,
+// unless a LSP client still has the file open.
 func (fm *FileManager) didClose(name string) error {
 	fm.mu.Lock()
 	defer fm.mu.Unlock()
@@ -139,6 +164,9 @@ func (fm *FileManager) didClose(name string) error {
 	}
 	delete(fm.wins, name)
 
+	if fm.Clients.isOpen(text.ToURI(name)) {
+		return nil
+	}
 	return fm.withClient(-1, name, func(c *Client, _ *acmeutil.Win) error {
 		return lsp.DidClose(context.Background(), c, name)
 	})
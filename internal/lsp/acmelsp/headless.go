@@ -0,0 +1,249 @@
+package acmelsp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tw4452852/acme-lsp/internal/acme"
+	"github.com/tw4452852/acme-lsp/internal/acmeutil"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+// editorFile is the file commands operate on: an acme window,
+// or a headlessFile when running without acme.
+type editorFile interface {
+	text.AddressableFile
+
+	// CloseFiles releases the resources associated with the file.
+	CloseFiles()
+}
+
+// headlessFile is a file read from disk or from acme,
+// which commands operate on without an acme window.
+type headlessFile struct {
+	*text.Buffer
+	winid  int  // acme window containing the file, or 0 if read from disk
+	opened bool // file was opened with the server using DidOpen
+}
+
+func (f *headlessFile) CloseFiles() {}
+
+// ParseFileAddr parses file address s of the form path:line:col, where
+// line and col are 1-based and col counts runes. The column may be
+// omitted, and the path is made absolute.
+func ParseFileAddr(s string) (filename string, line, col int, err error) {
+	addr := s
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndex(addr, ":")
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(addr[i+1:])
+		if err != nil {
+			break
+		}
+		if n < 1 {
+			return "", 0, 0, fmt.Errorf("invalid file address %q: line and column start at 1", s)
+		}
+		nums = append([]int{n}, nums...)
+		addr = addr[:i]
+	}
+	switch len(nums) {
+	case 0:
+		return "", 0, 0, fmt.Errorf("file address %q is not of the form path:line:col", s)
+	case 1:
+		nums = append(nums, 1)
+	}
+	if addr == "" {
+		return "", 0, 0, fmt.Errorf("file address %q is missing the path", s)
+	}
+	filename, err = filepath.Abs(addr)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return filename, nums[0], nums[1], nil
+}
+
+// FileRemoteCmd returns a RemoteCmd which executes commands at the file
+// address addr (see ParseFileAddr) instead of the cursor position of an
+// acme window. The file is read from acme if it's open there, or from
+// disk otherwise. Files edited by commands (e.g. Rename) are written back
+// to disk unless they are open in acme. Close must be called when done.
+func FileRemoteCmd(server proxy.Server, addr string) (*RemoteCmd, error) {
+	name, line, col, err := ParseFileAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	body, winid, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f := &headlessFile{
+		Buffer: text.NewBuffer(name, body),
+		winid:  winid,
+	}
+	if err := f.SetPosition(line-1, col-1); err != nil {
+		return nil, err
+	}
+	rc := NewRemoteCmd(server, winid)
+	rc.file = f
+	return rc, nil
+}
+
+// Close closes the file opened with the server, if any.
+func (rc *RemoteCmd) Close(ctx context.Context) error {
+	if rc.file == nil || !rc.file.opened {
+		return nil
+	}
+	rc.file.opened = false
+	return rc.server.DidClose(ctx, &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: text.ToURI(rc.file.name()),
+		},
+	})
+}
+
+func (f *headlessFile) name() string {
+	name, _ := f.Filename()
+	return name
+}
+
+// openFile returns the file commands operate on.
+// The caller must call CloseFiles when done.
+func (rc *RemoteCmd) openFile() (editorFile, error) {
+	if rc.file != nil {
+		return rc.file, nil
+	}
+	w, err := acmeutil.OpenWin(rc.winid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to to open window %v: %v", rc.winid, err)
+	}
+	return w, nil
+}
+
+// syncFile tells the server about the text of the headless file. A file
+// open in acme is already known to acme-lsp, so only its changes are sent.
+func (rc *RemoteCmd) syncFile(ctx context.Context) error {
+	f := rc.file
+	if f.winid > 0 || f.opened {
+		return rc.server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{
+					URI: text.ToURI(f.name()),
+				},
+			},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{
+				{
+					Text: string(f.Bytes()),
+				},
+			},
+		})
+	}
+	err := rc.server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:  text.ToURI(f.name()),
+			Text: string(f.Bytes()),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	f.opened = true
+	return nil
+}
+
+// readFile returns the text of the named file. The text is read from
+// the acme window containing the file, if there is one, in which case
+// the window ID is returned too. Otherwise, the file is read from disk.
+func readFile(name string) (body []byte, winid int, err error) {
+	if wins, err := acme.Windows(); err == nil {
+		for _, info := range wins {
+			if info.Name != name {
+				continue
+			}
+			w, err := acmeutil.OpenWin(info.ID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to open window %v: %v", info.ID, err)
+			}
+			defer w.CloseFiles()
+
+			body, err := w.ReadAll("body")
+			if err != nil {
+				return nil, 0, err
+			}
+			return body, info.ID, nil
+		}
+	}
+	body, err = ioutil.ReadFile(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, 0, nil
+}
+
+// editWorkspace applies the workspace edit. In headless mode, files
+// open in acme are edited there and other files are written to disk.
+// If rc.Diff is set, the changes are printed as a diff instead.
+func (rc *RemoteCmd) editWorkspace(we *protocol.WorkspaceEdit) error {
+	if rc.file == nil {
+		return editWorkspace(we)
+	}
+	changes := workspaceChanges(we)
+	uris := make([]string, 0, len(changes))
+	for uri := range changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		name := text.ToPath(uri)
+		body, winid, err := readFile(name)
+		if err != nil {
+			return err
+		}
+		if winid > 0 && !rc.Diff {
+			w, err := acmeutil.OpenWin(winid)
+			if err != nil {
+				return fmt.Errorf("failed to open window %v: %v", winid, err)
+			}
+			err = text.Edit(w, changes[uri])
+			w.CloseFiles()
+			if err != nil {
+				return fmt.Errorf("failed to apply edits to window %v: %v", winid, err)
+			}
+			continue
+		}
+		b := text.NewBuffer(name, body)
+		if err := text.Edit(b, changes[uri]); err != nil {
+			return fmt.Errorf("failed to apply edits to %v: %v", name, err)
+		}
+		if rc.Diff {
+			if err := printDiff(rc.Stdout, name, body, b.Bytes()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFile(name, b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile replaces the content of the named file, keeping its permissions.
+func writeFile(name string, data []byte) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, fi.Mode().Perm())
+}
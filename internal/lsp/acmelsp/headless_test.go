@@ -0,0 +1,84 @@
+package acmelsp
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFileAddr(t *testing.T) {
+	abs := func(name string) string {
+		s, err := filepath.Abs(name)
+		if err != nil {
+			t.Fatalf("Abs failed: %v", err)
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		addr      string
+		name      string
+		line, col int
+	}{
+		{"/home/gopher/main.go:12:5", "/home/gopher/main.go", 12, 5},
+		{"/home/gopher/main.go:12", "/home/gopher/main.go", 12, 1},
+		{"main.go:3:1", abs("main.go"), 3, 1},
+		{"a:b.go:7:2", abs("a:b.go"), 7, 2},
+	} {
+		name, line, col, err := ParseFileAddr(tc.addr)
+		if err != nil {
+			t.Errorf("ParseFileAddr(%q) failed: %v", tc.addr, err)
+			continue
+		}
+		if name != tc.name || line != tc.line || col != tc.col {
+			t.Errorf("ParseFileAddr(%q) = %q, %v, %v; want %q, %v, %v",
+				tc.addr, name, line, col, tc.name, tc.line, tc.col)
+		}
+	}
+	for _, addr := range []string{"main.go", ":1:2", "main.go:0:1"} {
+		if _, _, _, err := ParseFileAddr(addr); err == nil {
+			t.Errorf("ParseFileAddr(%q) succeeded", addr)
+		}
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	const name = "/home/gopher/main.go"
+	for _, tc := range []struct {
+		old, new string
+		want     string
+	}{
+		{"a\n", "a\n", ""},
+		{
+			"a\nb\nc\n", "a\nB\nc\n",
+			"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"a\nb\n", "x\na\nb\n",
+			"@@ -1,2 +1,3 @@\n+x\n a\n b\n",
+		},
+		{
+			"a\nb", "a\nb\n",
+			"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "1\nII\n3\n4\n5\n6\n7\n8\n9\nX\n",
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+II\n 3\n 4\n 5\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+X\n",
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n", "1\nII\n3\n4\n5\n6\n7\nVIII\n",
+			"@@ -1,8 +1,8 @@\n 1\n-2\n+II\n 3\n 4\n 5\n 6\n 7\n-8\n+VIII\n",
+		},
+	} {
+		var buf bytes.Buffer
+		if err := printDiff(&buf, name, []byte(tc.old), []byte(tc.new)); err != nil {
+			t.Fatalf("printDiff failed: %v", err)
+		}
+		want := tc.want
+		if want != "" {
+			want = "--- " + name + ".orig\n+++ " + name + "\n" + want
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("printDiff of %q to %q wrote %q; want %q", tc.old, tc.new, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package acmelsp
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+)
+
+// maxJumps is the maximum number of entries kept in the navigation history.
+const maxJumps = 100
+
+// jumpList is the navigation history of locations plumbed by acme-lsp.
+// Like a web browser's history, moving back and then jumping somewhere
+// else discards the entries forward of the current one.
+type jumpList struct {
+	entries []protocol.Location
+	cur     int // index of the current entry
+	mu      sync.Mutex
+}
+
+// add records a jump from one location to another.
+// The from location replaces the current entry.
+func (l *jumpList) add(from, to protocol.Location) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) == 0 {
+		l.entries = []protocol.Location{from}
+	} else {
+		l.entries = l.entries[:l.cur+1]
+		l.entries[l.cur] = from
+	}
+	l.entries = append(l.entries, to)
+	if n := len(l.entries) - maxJumps; n > 0 {
+		l.entries = append([]protocol.Location(nil), l.entries[n:]...)
+	}
+	l.cur = len(l.entries) - 1
+}
+
+// move moves the current entry by delta entries and returns its location.
+func (l *jumpList) move(delta int) (*protocol.Location, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := l.cur + delta
+	switch {
+	case len(l.entries) == 0:
+		return nil, fmt.Errorf("navigation history is empty")
+	case i < 0:
+		return nil, fmt.Errorf("already at the oldest entry of navigation history")
+	case i >= len(l.entries):
+		return nil, fmt.Errorf("already at the newest entry of navigation history")
+	}
+	l.cur = i
+	loc := l.entries[i]
+	return &loc, nil
+}
+
+func (l *jumpList) list() *proxy.JumpsResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return &proxy.JumpsResult{
+		Entries: append([]protocol.Location(nil), l.entries...),
+		Current: l.cur,
+	}
+}
+
+// PrintJumps writes the navigation history to w, one location per line,
+// with the current entry marked by ">".
+func PrintJumps(w io.Writer, jumps *proxy.JumpsResult) error {
+	if len(jumps.Entries) == 0 {
+		_, err := fmt.Fprintf(w, "No jumps.\n")
+		return err
+	}
+	for i := range jumps.Entries {
+		mark := " "
+		if i == jumps.Current {
+			mark = ">"
+		}
+		_, err := fmt.Fprintf(w, "%v %v\n", mark, lsp.LocationLink(&jumps.Entries[i]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
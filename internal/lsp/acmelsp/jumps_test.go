@@ -0,0 +1,101 @@
+package acmelsp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+)
+
+func lineLocation(line float64) protocol.Location {
+	pos := protocol.Position{Line: line}
+	return protocol.Location{
+		URI:   "file:///home/gopher/main.go",
+		Range: protocol.Range{Start: pos, End: pos},
+	}
+}
+
+func TestJumpList(t *testing.T) {
+	var l jumpList
+
+	if _, err := l.move(-1); err == nil {
+		t.Errorf("moving back in empty history succeeded")
+	}
+
+	l.add(lineLocation(1), lineLocation(10))
+	l.add(lineLocation(11), lineLocation(20))
+
+	loc, err := l.move(-1)
+	if err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if want := lineLocation(11); *loc != want {
+		t.Errorf("moved back to %v; want %v", *loc, want)
+	}
+	loc, err = l.move(-1)
+	if err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if want := lineLocation(1); *loc != want {
+		t.Errorf("moved back to %v; want %v", *loc, want)
+	}
+	if _, err := l.move(-1); err == nil {
+		t.Errorf("moving back past the oldest entry succeeded")
+	}
+	loc, err = l.move(1)
+	if err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	if want := lineLocation(11); *loc != want {
+		t.Errorf("moved forward to %v; want %v", *loc, want)
+	}
+
+	// Jumping from the middle of the history discards forward entries.
+	l.add(lineLocation(12), lineLocation(30))
+	want := []protocol.Location{lineLocation(1), lineLocation(12), lineLocation(30)}
+	got := l.list()
+	if len(got.Entries) != len(want) {
+		t.Fatalf("history has %v entries; want %v", len(got.Entries), len(want))
+	}
+	for i := range want {
+		if got.Entries[i] != want[i] {
+			t.Errorf("entry %v is %v; want %v", i, got.Entries[i], want[i])
+		}
+	}
+	if got.Current != 2 {
+		t.Errorf("current entry is %v; want 2", got.Current)
+	}
+	if _, err := l.move(1); err == nil {
+		t.Errorf("moving forward past the newest entry succeeded")
+	}
+}
+
+func TestJumpListLimit(t *testing.T) {
+	var l jumpList
+	for i := 0; i < 2*maxJumps; i++ {
+		l.add(lineLocation(float64(i)), lineLocation(float64(i+1)))
+	}
+	got := l.list()
+	if len(got.Entries) != maxJumps {
+		t.Errorf("history has %v entries; want %v", len(got.Entries), maxJumps)
+	}
+	if want := lineLocation(2 * maxJumps); got.Entries[got.Current] != want {
+		t.Errorf("current entry is %v; want %v", got.Entries[got.Current], want)
+	}
+}
+
+func TestPrintJumps(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintJumps(&buf, &proxy.JumpsResult{
+		Entries: []protocol.Location{lineLocation(0), lineLocation(9)},
+		Current: 1,
+	})
+	if err != nil {
+		t.Fatalf("PrintJumps failed: %v", err)
+	}
+	want := "  /home/gopher/main.go:1:1-1:1\n> /home/gopher/main.go:10:1-10:1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("PrintJumps wrote %q; want %q", got, want)
+	}
+}
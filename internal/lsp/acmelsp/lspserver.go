@@ -0,0 +1,750 @@
+package acmelsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+	"github.com/tw4452852/acme-lsp/internal/p9service"
+)
+
+// LSPClientSet keeps track of LSP clients (e.g. other editors) connected
+// to acme-lsp using the standard LSP protocol (see ListenAndServeLSP).
+// It's a DiagnosticsWriter which sends diagnostics to the connected
+// clients with the document open, in addition to writing them to the
+// wrapped DiagnosticsWriter.
+type LSPClientSet struct {
+	w       DiagnosticsWriter
+	clients map[*lspServer]struct{}
+	docs    map[protocol.DocumentURI]int // number of clients with the document open
+	mu      sync.Mutex
+}
+
+// NewLSPClientSet returns a LSPClientSet wrapping DiagnosticsWriter w.
+func NewLSPClientSet(w DiagnosticsWriter) *LSPClientSet {
+	return &LSPClientSet{
+		w:       w,
+		clients: make(map[*lspServer]struct{}),
+		docs:    make(map[protocol.DocumentURI]int),
+	}
+}
+
+func (cs *LSPClientSet) WriteDiagnostics(params *protocol.PublishDiagnosticsParams) {
+	cs.w.WriteDiagnostics(params)
+
+	cs.mu.Lock()
+	var clients []protocol.Client
+	for s := range cs.clients {
+		if s.isOpen(params.URI) {
+			clients = append(clients, s.client)
+		}
+	}
+	cs.mu.Unlock()
+
+	for _, c := range clients {
+		if err := c.PublishDiagnostics(context.Background(), params); err != nil {
+			dprintf("failed to send diagnostics to LSP client: %v\n", err)
+		}
+	}
+}
+
+// open increments the number of clients with the document open,
+// and returns true if it wasn't open before.
+func (cs *LSPClientSet) open(uri protocol.DocumentURI) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.docs[uri]++
+	return cs.docs[uri] == 1
+}
+
+// close decrements the number of clients with the document open,
+// and returns true if it's no longer open.
+func (cs *LSPClientSet) close(uri protocol.DocumentURI) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.docs[uri]--
+	if cs.docs[uri] > 0 {
+		return false
+	}
+	delete(cs.docs, uri)
+	return true
+}
+
+// isOpen returns true if a client has the document open.
+// It returns false if cs is nil.
+func (cs *LSPClientSet) isOpen(uri protocol.DocumentURI) bool {
+	if cs == nil {
+		return false
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.docs[uri] > 0
+}
+
+// ListenAndServeLSP listens for connections from LSP clients on the
+// network address given by cfg.LSPNetwork and cfg.LSPAddress. Requests
+// from the clients are forwarded to the LSP servers in ss based on the
+// document URI, so that the clients share the servers with acme.
+func ListenAndServeLSP(ctx context.Context, cfg *config.Config, ss *ServerSet, fm *FileManager, cs *LSPClientSet) error {
+	ln, err := p9service.Listen(ctx, cfg.LSPNetwork, cfg.LSPAddress)
+	if err != nil {
+		return err
+	}
+	// See ListenAndServeProxy.
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go serveLSP(ctx, conn, ss, fm, cs)
+	}
+}
+
+func serveLSP(ctx context.Context, conn net.Conn, ss *ServerSet, fm *FileManager, cs *LSPClientSet) {
+	s := &lspServer{
+		ss:      ss,
+		fm:      fm,
+		clients: cs,
+		netConn: conn,
+		opened:  make(map[protocol.DocumentURI]bool),
+	}
+	stream := jsonrpc2.NewHeaderStream(conn, conn)
+	ctx, rpc, client := protocol.NewServer(ctx, stream, s)
+	s.client = client
+
+	if err := rpc.Run(ctx); err != nil {
+		dprintf("LSP client connection closed: %v\n", err)
+	}
+	s.closeAll(context.Background())
+	conn.Close()
+}
+
+// lspServer implements the LSP server for one LSP client connection.
+type lspServer struct {
+	ss      *ServerSet
+	fm      *FileManager
+	clients *LSPClientSet
+	client  protocol.Client
+	netConn net.Conn
+
+	mu     sync.Mutex
+	opened map[protocol.DocumentURI]bool // documents opened by the client
+}
+
+// isOpen returns true if the client has the document open.
+func (s *lspServer) isOpen(uri protocol.DocumentURI) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.opened[uri]
+}
+
+// resolveData replaces the data field of the completion items, code
+// lenses and document links sent to the client. Resolve requests don't
+// have a document, so it tells which server the item came from.
+type resolveData struct {
+	URI  protocol.DocumentURI `json:"uri"`
+	Data interface{}          `json:"data,omitempty"` // original data field
+}
+
+// serverForResolve returns the server for the document of an item to
+// resolve, given its data field, and the item's original data field.
+func (s *lspServer) serverForResolve(data interface{}) (*Server, interface{}, error) {
+	var rd resolveData
+	b, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(b, &rd)
+	}
+	if err != nil || rd.URI == "" {
+		return nil, nil, fmt.Errorf("item to resolve wasn't sent by acme-lsp")
+	}
+	srv, err := serverForURI(s.ss, rd.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+	return srv, rd.Data, nil
+}
+
+// openInAcme returns true if the document is open in acme,
+// in which case acme-lsp already keeps it in sync with the server.
+func (s *lspServer) openInAcme(uri protocol.DocumentURI) bool {
+	return s.fm != nil && s.fm.isOpen(text.ToPath(uri))
+}
+
+// closeAll closes the documents opened by the client.
+func (s *lspServer) closeAll(ctx context.Context) {
+	s.clients.mu.Lock()
+	delete(s.clients.clients, s)
+	s.clients.mu.Unlock()
+
+	s.mu.Lock()
+	var uris []protocol.DocumentURI
+	for uri := range s.opened {
+		uris = append(uris, uri)
+	}
+	s.mu.Unlock()
+
+	for _, uri := range uris {
+		err := s.DidClose(ctx, &protocol.DidCloseTextDocumentParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+		if err != nil {
+			log.Printf("failed to close %v: %v", uri, err)
+		}
+	}
+}
+
+// Initialize starts all the servers, so that the capabilities provided
+// by any of them can be returned.
+func (s *lspServer) Initialize(ctx context.Context, params *protocol.ParamInitia) (*protocol.InitializeResult, error) {
+	s.clients.mu.Lock()
+	s.clients.clients[s] = struct{}{}
+	s.clients.mu.Unlock()
+
+	var caps []*protocol.ServerCapabilities
+	for _, info := range s.ss.Data {
+		srv, err := info.start(s.ss.ClientConfig(info))
+		if err != nil {
+			log.Printf("could not start language server %v: %v", info.ServerKey, err)
+			continue
+		}
+		caps = append(caps, &srv.Client.initResult().Capabilities)
+	}
+	result := &protocol.InitializeResult{
+		Capabilities: mergeCapabilities(caps),
+	}
+	result.ServerInfo = &struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}{
+		Name: "acme-lsp",
+	}
+	return result, nil
+}
+
+// mergeCapabilities returns the capabilities provided by any of the
+// given servers, as far as acme-lsp can forward the requests to them.
+// Documents are always synced in full.
+func mergeCapabilities(caps []*protocol.ServerCapabilities) protocol.ServerCapabilities {
+	m := protocol.ServerCapabilities{
+		TextDocumentSync: protocol.Full,
+	}
+	var cmds []string
+	for _, c := range caps {
+		if p := c.CompletionProvider; p != nil {
+			if m.CompletionProvider == nil {
+				m.CompletionProvider = &protocol.CompletionOptions{}
+			}
+			m.CompletionProvider.TriggerCharacters = union(m.CompletionProvider.TriggerCharacters, p.TriggerCharacters)
+			m.CompletionProvider.ResolveProvider = m.CompletionProvider.ResolveProvider || p.ResolveProvider
+		}
+		if p := c.SignatureHelpProvider; p != nil {
+			if m.SignatureHelpProvider == nil {
+				m.SignatureHelpProvider = &protocol.SignatureHelpOptions{}
+			}
+			m.SignatureHelpProvider.TriggerCharacters = union(m.SignatureHelpProvider.TriggerCharacters, p.TriggerCharacters)
+			m.SignatureHelpProvider.RetriggerCharacters = union(m.SignatureHelpProvider.RetriggerCharacters, p.RetriggerCharacters)
+		}
+		if p := c.CodeLensProvider; p != nil {
+			if m.CodeLensProvider == nil {
+				m.CodeLensProvider = &protocol.CodeLensOptions{}
+			}
+			m.CodeLensProvider.ResolveProvider = m.CodeLensProvider.ResolveProvider || p.ResolveProvider
+		}
+		if p := c.DocumentLinkProvider; p != nil {
+			if m.DocumentLinkProvider == nil {
+				m.DocumentLinkProvider = &protocol.DocumentLinkOptions{}
+			}
+			m.DocumentLinkProvider.ResolveProvider = m.DocumentLinkProvider.ResolveProvider || p.ResolveProvider
+		}
+		if p := c.DocumentOnTypeFormattingProvider; p != nil {
+			if m.DocumentOnTypeFormattingProvider == nil {
+				m.DocumentOnTypeFormattingProvider = &protocol.DocumentOnTypeFormattingOptions{
+					FirstTriggerCharacter: p.FirstTriggerCharacter,
+				}
+			} else if p.FirstTriggerCharacter != m.DocumentOnTypeFormattingProvider.FirstTriggerCharacter {
+				m.DocumentOnTypeFormattingProvider.MoreTriggerCharacter = union(m.DocumentOnTypeFormattingProvider.MoreTriggerCharacter, []string{p.FirstTriggerCharacter})
+			}
+			m.DocumentOnTypeFormattingProvider.MoreTriggerCharacter = union(m.DocumentOnTypeFormattingProvider.MoreTriggerCharacter, p.MoreTriggerCharacter)
+		}
+		if p := c.ExecuteCommandProvider; p != nil {
+			cmds = union(cmds, p.Commands)
+		}
+		m.HoverProvider = m.HoverProvider || c.HoverProvider
+		m.DeclarationProvider = m.DeclarationProvider || c.DeclarationProvider
+		m.DefinitionProvider = m.DefinitionProvider || c.DefinitionProvider
+		m.TypeDefinitionProvider = m.TypeDefinitionProvider || c.TypeDefinitionProvider
+		m.ImplementationProvider = m.ImplementationProvider || c.ImplementationProvider
+		m.ReferencesProvider = m.ReferencesProvider || c.ReferencesProvider
+		m.DocumentHighlightProvider = m.DocumentHighlightProvider || c.DocumentHighlightProvider
+		m.DocumentSymbolProvider = m.DocumentSymbolProvider || c.DocumentSymbolProvider
+		m.WorkspaceSymbolProvider = m.WorkspaceSymbolProvider || c.WorkspaceSymbolProvider
+		m.DocumentFormattingProvider = m.DocumentFormattingProvider || c.DocumentFormattingProvider
+		m.DocumentRangeFormattingProvider = m.DocumentRangeFormattingProvider || c.DocumentRangeFormattingProvider
+		m.FoldingRangeProvider = m.FoldingRangeProvider || c.FoldingRangeProvider
+		m.SelectionRangeProvider = m.SelectionRangeProvider || c.SelectionRangeProvider
+		if provided(c.CodeActionProvider) {
+			m.CodeActionProvider = true
+		}
+		if provided(c.ColorProvider) {
+			m.ColorProvider = true
+		}
+		if provided(c.RenameProvider) {
+			m.RenameProvider = true
+		}
+		if provided(c.InlayHintProvider) {
+			m.InlayHintProvider = true
+		}
+	}
+	if cmds != nil {
+		m.ExecuteCommandProvider = &protocol.ExecuteCommandOptions{
+			Commands: cmds,
+		}
+	}
+	return m
+}
+
+// union returns a with the strings in b that aren't in a appended.
+func union(a, b []string) []string {
+	for _, t := range b {
+		found := false
+		for _, u := range a {
+			if t == u {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, t)
+		}
+	}
+	return a
+}
+
+func (s *lspServer) Initialized(context.Context, *protocol.InitializedParams) error {
+	return nil
+}
+
+// Shutdown does nothing since the servers are shared with acme-lsp.
+func (s *lspServer) Shutdown(context.Context) error {
+	return nil
+}
+
+func (s *lspServer) Exit(context.Context) error {
+	return s.netConn.Close()
+}
+
+// DidChangeConfiguration is ignored since the
+// servers are configured by acme-lsp.
+func (s *lspServer) DidChangeConfiguration(context.Context, *protocol.DidChangeConfigurationParams) error {
+	return nil
+}
+
+func (s *lspServer) DidChangeWorkspaceFolders(ctx context.Context, params *protocol.DidChangeWorkspaceFoldersParams) error {
+	return s.ss.DidChangeWorkspaceFolders(ctx, params.Event.Added, params.Event.Removed)
+}
+
+func (s *lspServer) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	changes := make(map[*Server][]protocol.FileEvent)
+	for _, ev := range params.Changes {
+		srv, err := serverForURI(s.ss, ev.URI)
+		if err != nil {
+			continue
+		}
+		changes[srv] = append(changes[srv], ev)
+	}
+	for srv, evs := range changes {
+		err := srv.Client.DidChangeWatchedFiles(ctx, &protocol.DidChangeWatchedFilesParams{
+			Changes: evs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DidOpen opens the document with the server, unless
+// it's already open in acme or by another client.
+func (s *lspServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	uri := params.TextDocument.URI
+	srv, err := serverForURI(s.ss, uri)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.opened[uri] {
+		s.mu.Unlock()
+		return fmt.Errorf("document already open: %v", uri)
+	}
+	s.opened[uri] = true
+	s.mu.Unlock()
+
+	if !s.clients.open(uri) || s.openInAcme(uri) {
+		return srv.Client.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{
+				{
+					Text: params.TextDocument.Text,
+				},
+			},
+		})
+	}
+	return srv.Client.DidOpen(ctx, params)
+}
+
+// DidClose closes the document with the server, unless
+// it's still open in acme or by another client.
+func (s *lspServer) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	uri := params.TextDocument.URI
+	s.mu.Lock()
+	if !s.opened[uri] {
+		s.mu.Unlock()
+		return fmt.Errorf("document not open: %v", uri)
+	}
+	delete(s.opened, uri)
+	s.mu.Unlock()
+
+	if !s.clients.close(uri) || s.openInAcme(uri) {
+		return nil
+	}
+	srv, err := serverForURI(s.ss, uri)
+	if err != nil {
+		return err
+	}
+	return srv.Client.DidClose(ctx, params)
+}
+
+func (s *lspServer) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	return srv.Client.DidChange(ctx, params)
+}
+
+func (s *lspServer) DidSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	return srv.Client.DidSave(ctx, params)
+}
+
+func (s *lspServer) WillSave(ctx context.Context, params *protocol.WillSaveTextDocumentParams) error {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	return srv.Client.WillSave(ctx, params)
+}
+
+func (s *lspServer) WillSaveWaitUntil(ctx context.Context, params *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.WillSaveWaitUntil(ctx, params)
+}
+
+func (s *lspServer) Progress(context.Context, *protocol.ProgressParams) error {
+	return nil
+}
+
+func (s *lspServer) SetTraceNotification(context.Context, *protocol.SetTraceParams) error {
+	return nil
+}
+
+func (s *lspServer) LogTraceNotification(context.Context, *protocol.LogTraceParams) error {
+	return nil
+}
+
+func (s *lspServer) Implementation(ctx context.Context, params *protocol.ImplementationParams) ([]protocol.Location, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Implementation(ctx, params)
+}
+
+func (s *lspServer) TypeDefinition(ctx context.Context, params *protocol.TypeDefinitionParams) ([]protocol.Location, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.TypeDefinition(ctx, params)
+}
+
+func (s *lspServer) DocumentColor(ctx context.Context, params *protocol.DocumentColorParams) ([]protocol.ColorInformation, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.DocumentColor(ctx, params)
+}
+
+func (s *lspServer) ColorPresentation(ctx context.Context, params *protocol.ColorPresentationParams) ([]protocol.ColorPresentation, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.ColorPresentation(ctx, params)
+}
+
+func (s *lspServer) FoldingRange(ctx context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.FoldingRange(ctx, params)
+}
+
+func (s *lspServer) Declaration(ctx context.Context, params *protocol.DeclarationParams) ([]protocol.DeclarationLink, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Declaration(ctx, params)
+}
+
+func (s *lspServer) SelectionRange(ctx context.Context, params *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.SelectionRange(ctx, params)
+}
+
+func (s *lspServer) InlayHint(ctx context.Context, params *protocol.InlayHintParams) ([]protocol.InlayHint, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.InlayHint(ctx, params)
+}
+
+func (s *lspServer) Completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	list, err := srv.Client.Completion(ctx, params)
+	if err != nil || list == nil {
+		return list, err
+	}
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.Data = &resolveData{URI: params.TextDocument.URI, Data: item.Data}
+	}
+	return list, nil
+}
+
+func (s *lspServer) Resolve(ctx context.Context, params *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	srv, data, err := s.serverForResolve(params.Data)
+	if err != nil {
+		return nil, err
+	}
+	params.Data = data
+	return srv.Client.Resolve(ctx, params)
+}
+
+func (s *lspServer) Hover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Hover(ctx, params)
+}
+
+func (s *lspServer) SignatureHelp(ctx context.Context, params *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.SignatureHelp(ctx, params)
+}
+
+func (s *lspServer) Definition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Definition(ctx, params)
+}
+
+func (s *lspServer) References(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.References(ctx, params)
+}
+
+func (s *lspServer) DocumentHighlight(ctx context.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.DocumentHighlight(ctx, params)
+}
+
+func (s *lspServer) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) (*protocol.DocumentSymbols, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.DocumentSymbol(ctx, params)
+}
+
+func (s *lspServer) CodeAction(ctx context.Context, params *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.CodeAction(ctx, params)
+}
+
+// Symbol returns the workspace symbols found by all the servers that are running.
+func (s *lspServer) Symbol(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	syms := []protocol.SymbolInformation{}
+	for _, info := range s.ss.Data {
+		srv := info.running()
+		if srv == nil {
+			continue
+		}
+		l, err := srv.Client.Symbol(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		syms = append(syms, l...)
+	}
+	return syms, nil
+}
+
+func (s *lspServer) CodeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	lenses, err := srv.Client.CodeLens(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lenses {
+		l := &lenses[i]
+		l.Data = &resolveData{URI: params.TextDocument.URI, Data: l.Data}
+	}
+	return lenses, nil
+}
+
+func (s *lspServer) ResolveCodeLens(ctx context.Context, params *protocol.CodeLens) (*protocol.CodeLens, error) {
+	srv, data, err := s.serverForResolve(params.Data)
+	if err != nil {
+		return nil, err
+	}
+	params.Data = data
+	return srv.Client.ResolveCodeLens(ctx, params)
+}
+
+func (s *lspServer) DocumentLink(ctx context.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	links, err := srv.Client.DocumentLink(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		l := &links[i]
+		l.Data = &resolveData{URI: params.TextDocument.URI, Data: l.Data}
+	}
+	return links, nil
+}
+
+func (s *lspServer) ResolveDocumentLink(ctx context.Context, params *protocol.DocumentLink) (*protocol.DocumentLink, error) {
+	srv, data, err := s.serverForResolve(params.Data)
+	if err != nil {
+		return nil, err
+	}
+	params.Data = data
+	return srv.Client.ResolveDocumentLink(ctx, params)
+}
+
+func (s *lspServer) Formatting(ctx context.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Formatting(ctx, params)
+}
+
+func (s *lspServer) RangeFormatting(ctx context.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.RangeFormatting(ctx, params)
+}
+
+func (s *lspServer) OnTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.OnTypeFormatting(ctx, params)
+}
+
+func (s *lspServer) Rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.Rename(ctx, params)
+}
+
+func (s *lspServer) PrepareRename(ctx context.Context, params *protocol.PrepareRenameParams) (*protocol.Range, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return srv.Client.PrepareRename(ctx, params)
+}
+
+// ExecuteCommand executes the command on the running server which
+// supports it. Workspace edits requested by the server meanwhile are
+// sent to the client.
+func (s *lspServer) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	for _, info := range s.ss.Data {
+		srv := info.running()
+		if srv == nil {
+			continue
+		}
+		p := srv.Client.initResult().Capabilities.ExecuteCommandProvider
+		if p == nil {
+			continue
+		}
+		for _, cmd := range p.Commands {
+			if cmd == params.Command {
+				return srv.Client.executeCommand(ctx, params, s.client)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown command %q", params.Command)
+}
@@ -0,0 +1,359 @@
+package acmelsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+type nopDiagnosticsWriter struct {
+	n int
+}
+
+func (w *nopDiagnosticsWriter) WriteDiagnostics(*protocol.PublishDiagnosticsParams) {
+	w.n++
+}
+
+func TestLSPClientSetDocs(t *testing.T) {
+	cs := NewLSPClientSet(&nopDiagnosticsWriter{})
+	uri := protocol.DocumentURI("file:///home/gopher/main.go")
+
+	if !cs.open(uri) {
+		t.Errorf("first open returned false")
+	}
+	if cs.open(uri) {
+		t.Errorf("second open returned true")
+	}
+	if cs.close(uri) {
+		t.Errorf("close returned true while document is still open")
+	}
+	if !cs.close(uri) {
+		t.Errorf("last close returned false")
+	}
+}
+
+// recordServer is a LSP server which records the
+// document notifications and hover requests it receives.
+type recordServer struct {
+	protocol.Server
+	client protocol.Client // sent workspace edits while executing commands
+	mu     sync.Mutex
+	calls  []string // e.g. "didOpen file:///home/gopher/main.go"
+}
+
+func (s *recordServer) record(method string, uri protocol.DocumentURI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf("%v %v", method, uri))
+}
+
+func (s *recordServer) recorded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.calls...)
+}
+
+func (s *recordServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	s.record("didOpen", params.TextDocument.URI)
+	return nil
+}
+
+func (s *recordServer) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	s.record("didChange", params.TextDocument.URI)
+	return nil
+}
+
+func (s *recordServer) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	s.record("didClose", params.TextDocument.URI)
+	return nil
+}
+
+func (s *recordServer) Hover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
+	s.record("hover", params.TextDocument.URI)
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.PlainText,
+			Value: "func main()",
+		},
+	}, nil
+}
+
+func (s *recordServer) Completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
+	return &protocol.CompletionList{
+		Items: []protocol.CompletionItem{
+			{Label: "main", Data: 42},
+		},
+	}, nil
+}
+
+// Resolve sets the detail of the item to its data.
+func (s *recordServer) Resolve(ctx context.Context, params *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	item := *params
+	item.Detail = fmt.Sprint(params.Data)
+	return &item, nil
+}
+
+// ExecuteCommand asks the client to apply an edit labeled with the command.
+func (s *recordServer) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	return s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
+		Label: params.Command,
+	})
+}
+
+// newRecordServerSet returns a server set where
+// Go files are handled by a running recordServer.
+func newRecordServerSet(t *testing.T, dw DiagnosticsWriter) (*ServerSet, *recordServer) {
+	cfg := &config.Config{
+		File: config.File{
+			Servers: map[string]*config.Server{
+				"gopls": {
+					Command: []string{"gopls"},
+				},
+			},
+			FilenameHandlers: []config.FilenameHandler{
+				{
+					Pattern:   `\.go$`,
+					ServerKey: "gopls",
+				},
+			},
+		},
+	}
+	ss, err := NewServerSet(cfg, dw)
+	if err != nil {
+		t.Fatalf("NewServerSet failed: %v", err)
+	}
+	rs := &recordServer{}
+	info := ss.Data[0]
+	c := &Client{
+		Server: rs,
+		initializeResult: &protocol.InitializeResult{
+			Capabilities: protocol.ServerCapabilities{
+				CompletionProvider: &protocol.CompletionOptions{
+					TriggerCharacters: []string{"."},
+					ResolveProvider:   true,
+				},
+				HoverProvider: true,
+				ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+					Commands: []string{"gopls.tidy"},
+				},
+			},
+		},
+		cfg:  ss.ClientConfig(info),
+		docs: make(map[protocol.DocumentURI]*document),
+	}
+	rs.client = &clientHandler{client: c, cfg: c.cfg}
+	info.srv = &Server{
+		Client: c,
+	}
+	return ss, rs
+}
+
+// editClient is a LSP client which sends the
+// labels of the workspace edits it's asked to apply to a channel.
+type editClient struct {
+	*clientHandler
+	edits chan string
+}
+
+func (c *editClient) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
+	c.edits <- params.Label
+	return &protocol.ApplyWorkspaceEditResponse{Applied: true}, nil
+}
+
+// chanDiagnosticsWriter sends the diagnostics it's given to a channel.
+type chanDiagnosticsWriter chan *protocol.PublishDiagnosticsParams
+
+func (w chanDiagnosticsWriter) WriteDiagnostics(params *protocol.PublishDiagnosticsParams) {
+	w <- params
+}
+
+func TestServeLSP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := &nopDiagnosticsWriter{}
+	cs := NewLSPClientSet(w)
+	ss, rs := newRecordServerSet(t, cs)
+	c1, c2 := net.Pipe()
+	go serveLSP(ctx, c1, ss, nil, cs)
+
+	diags := make(chanDiagnosticsWriter, 1)
+	edits := make(chan string, 1)
+	ctx, rpc, server := protocol.NewClient(ctx, jsonrpc2.NewHeaderStream(c2, c2), &editClient{
+		clientHandler: &clientHandler{
+			cfg:        &ClientConfig{},
+			diagWriter: diags,
+		},
+		edits: edits,
+	})
+	go rpc.Run(ctx)
+
+	result, err := server.Initialize(ctx, &protocol.ParamInitia{})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if result.ServerInfo == nil || result.ServerInfo.Name != "acme-lsp" {
+		t.Errorf("server info is %v; want name acme-lsp", result.ServerInfo)
+	}
+	caps := result.Capabilities
+	if !caps.HoverProvider || caps.DefinitionProvider || caps.CodeLensProvider != nil {
+		t.Errorf("capabilities are %+v; want only those of the Go server", caps)
+	}
+	if p := caps.CompletionProvider; p == nil || !p.ResolveProvider || !cmp.Equal(p.TriggerCharacters, []string{"."}) {
+		t.Errorf("completion provider is %+v; want resolve provider with trigger character '.'", p)
+	}
+	if p := caps.ExecuteCommandProvider; p == nil || !cmp.Equal(p.Commands, []string{"gopls.tidy"}) {
+		t.Errorf("execute command provider is %+v; want command gopls.tidy", p)
+	}
+	hover := func(uri protocol.DocumentURI) (*protocol.Hover, error) {
+		return server.Hover(ctx, &protocol.HoverParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			},
+		})
+	}
+	uri := protocol.DocumentURI("file:///home/gopher/main.go")
+	h, err := hover(uri)
+	if err != nil {
+		t.Fatalf("Hover failed: %v", err)
+	}
+	if h.Contents.Value != "func main()" {
+		t.Errorf("hover is %q; want %q", h.Contents.Value, "func main()")
+	}
+	if want := []string{"hover " + string(uri)}; !cmp.Equal(rs.recorded(), want) {
+		t.Errorf("server got %v; want %v", rs.recorded(), want)
+	}
+	if _, err := hover("file:///home/gopher/main.py"); err == nil {
+		t.Errorf("Hover succeeded for a document without a server")
+	}
+
+	list, err := server.Completion(ctx, &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	item, err := server.Resolve(ctx, &list.Items[0])
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if item.Detail != "42" {
+		t.Errorf("server resolved item with data %v; want 42", item.Detail)
+	}
+	if _, err := server.Resolve(ctx, &protocol.CompletionItem{Label: "main"}); err == nil {
+		t.Errorf("Resolve succeeded for an item which wasn't sent by acme-lsp")
+	}
+
+	_, err = server.ExecuteCommand(ctx, &protocol.ExecuteCommandParams{Command: "gopls.tidy"})
+	if err != nil {
+		t.Fatalf("ExecuteCommand failed: %v", err)
+	}
+	select {
+	case label := <-edits:
+		if label != "gopls.tidy" {
+			t.Errorf("client was asked to apply edit %q; want gopls.tidy", label)
+		}
+	default:
+		t.Errorf("client wasn't asked to apply the edit of the command")
+	}
+
+	// Diagnostics are only sent to clients with the document open.
+	diagnostics := &protocol.PublishDiagnosticsParams{
+		URI: uri,
+		Diagnostics: []protocol.Diagnostic{
+			{Message: "undeclared name: fmt"},
+		},
+	}
+	cs.WriteDiagnostics(diagnostics)
+	err = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen failed: %v", err)
+	}
+	// Notifications are handled asynchronously.
+	for i := 0; !cs.isOpen(uri); i++ {
+		if i == 500 {
+			t.Fatalf("document wasn't opened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cs.WriteDiagnostics(diagnostics)
+	if w.n != 2 {
+		t.Errorf("wrapped writer got %v diagnostics; want 2", w.n)
+	}
+	select {
+	case p := <-diags:
+		if p.URI != uri || len(p.Diagnostics) != 1 {
+			t.Errorf("client got diagnostics %v; want one diagnostic for %v", p, uri)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("client didn't get the diagnostics")
+	}
+	select {
+	case p := <-diags:
+		t.Errorf("client got diagnostics %v twice", p)
+	default:
+	}
+}
+
+func TestFileManagerLSPClientDocs(t *testing.T) {
+	ctx := context.Background()
+	cs := NewLSPClientSet(&nopDiagnosticsWriter{})
+	ss, rs := newRecordServerSet(t, cs)
+	fm := &FileManager{
+		ss:      ss,
+		wins:    make(map[string]struct{}),
+		Clients: cs,
+	}
+	s := &lspServer{
+		ss:      ss,
+		fm:      fm,
+		clients: cs,
+		opened:  make(map[protocol.DocumentURI]bool),
+	}
+	name := "/home/gopher/main.go"
+	uri := text.ToURI(name)
+	c := ss.Data[0].srv.Client
+
+	// Opened by the client, then acme.
+	err := s.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen failed: %v", err)
+	}
+	if err := fm.open(ctx, c, name, nil); err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	// Closed by acme while the client has it open, then by the client.
+	if err := fm.didClose(name); err != nil {
+		t.Fatalf("didClose failed: %v", err)
+	}
+	err = s.DidClose(ctx, &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DidClose failed: %v", err)
+	}
+
+	want := []string{
+		"didOpen " + string(uri),
+		"didChange " + string(uri),
+		"didClose " + string(uri),
+	}
+	if got := rs.recorded(); !cmp.Equal(got, want) {
+		t.Errorf("server got %v; want %v", got, want)
+	}
+}
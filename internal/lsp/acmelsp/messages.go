@@ -0,0 +1,86 @@
+package acmelsp
+
+import (
+	"io"
+	"sync"
+
+	"github.com/tw4452852/acme-lsp/internal/acmeutil"
+)
+
+// messageWin is an io.Writer which appends to an acme window.
+// It's used to show messages sent by the LSP server (window/showMessage).
+// It will create the window on-demand, recreating it if necessary.
+type messageWin struct {
+	name string // window name
+	*acmeutil.Win
+
+	dead bool // window has been closed
+	mu   sync.Mutex
+}
+
+// NewMessageWriter returns an io.Writer which writes
+// to the "/LSP/Messages" acme window.
+func NewMessageWriter() io.Writer {
+	return &messageWin{
+		name: "/LSP/Messages",
+		dead: true,
+	}
+}
+
+func (mw *messageWin) restart() error {
+	if !mw.dead {
+		return nil
+	}
+	w, err := acmeutil.Hijack(mw.name)
+	if err != nil {
+		w, err = acmeutil.NewWin()
+		if err != nil {
+			return err
+		}
+		w.Name(mw.name)
+	}
+	mw.Win = w
+	mw.dead = false
+
+	go func() {
+		defer func() {
+			mw.mu.Lock()
+			mw.Del(true)
+			mw.CloseFiles()
+			mw.dead = true
+			mw.mu.Unlock()
+		}()
+
+		for ev := range mw.EventChan() {
+			if ev == nil {
+				return
+			}
+			switch ev.C2 {
+			case 'x', 'X': // execute
+				if string(ev.Text) == "Del" {
+					return
+				}
+			}
+			mw.WriteEvent(ev)
+		}
+	}()
+	return nil
+}
+
+// Write appends p to the body of the window and shows the end of the window.
+func (mw *messageWin) Write(p []byte) (int, error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	if err := mw.restart(); err != nil {
+		return 0, err
+	}
+	n, err := mw.Win.Write("body", p)
+	if err != nil {
+		return n, err
+	}
+	mw.Addr("$")
+	mw.Ctl("dot=addr")
+	mw.Ctl("clean")
+	return n, mw.Ctl("show")
+}
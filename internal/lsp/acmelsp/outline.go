@@ -0,0 +1,207 @@
+package acmelsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+)
+
+const outlineWinName = "/LSP/Outline"
+
+// outlineWin is an acme window showing the symbol tree
+// of the focused window, one symbol per line.
+type outlineWin struct {
+	*outputWin
+	uri   protocol.DocumentURI
+	syms  []*protocol.DocumentSymbol
+	lines []int // rune offset where the line of each symbol starts
+}
+
+// Outline creates an acme window showing the symbols of the focused window.
+// The symbol enclosing the cursor is marked with », and the outline is
+// refreshed when the focused window is edited or saved (see
+// notifyPosChange). Looking
+// (right-clicking) at a symbol in the outline jumps to it, using the
+// location opener given by cfg.
+func Outline(sm ServerMatcher, cfg *config.Config) error {
+	w, err := newOutputWin(sm, outlineWinName, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create acme window: %v", err)
+	}
+	defer w.Close()
+	ow := &outlineWin{outputWin: w}
+
+	fch := make(chan *focusWin)
+	go notifyPosChange(sm, fch)
+
+	for {
+		select {
+		case fw := <-fch:
+			ctx := context.Background()
+			server, found, err := sm.ServerMatch(ctx, fw.name)
+			if err != nil {
+				dprintf("failed to start language server: %v\n", err)
+			}
+			if found {
+				if err := ow.Update(ctx, fw, server); err != nil {
+					dprintf("outline update failed: %v\n", err)
+				}
+			}
+
+		case ev := <-w.event:
+			if ev == nil {
+				return nil
+			}
+			switch ev.C2 {
+			case 'x', 'X': // execute
+				if string(ev.Text) == "Del" {
+					return nil
+				}
+			case 'L': // look in body
+				if sym := ow.symbolAt(ev.Q0); sym != nil {
+					loc := protocol.Location{
+						URI:   ow.uri,
+						Range: sym.SelectionRange,
+					}
+					if err := OpenLocations([]protocol.Location{loc}, cfg.LocationOpener); err != nil {
+						dprintf("failed to open symbol location: %v\n", err)
+					}
+					continue
+				}
+			}
+			w.WriteEvent(ev)
+		}
+	}
+}
+
+// Update replaces the outline with the symbols of the focused window.
+func (w *outlineWin) Update(ctx context.Context, fw *focusWin, server proxy.Server) error {
+	// Assume file is already opened by file management.
+	if err := NewRemoteCmd(server, fw.id).DidChange(ctx); err != nil {
+		return fmt.Errorf("DidChange failed: %v", err)
+	}
+	pos, _, err := winPosition(fw.id)
+	if err != nil {
+		return err
+	}
+	result, err := server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+		TextDocument: pos.TextDocument,
+	})
+	if err != nil {
+		return err
+	}
+	syms := documentSymbolTree(result)
+
+	var cur *protocol.DocumentSymbol
+	if enclosing := enclosingSymbols(syms, pos.Position); len(enclosing) > 0 {
+		cur = enclosing[len(enclosing)-1]
+	}
+	var (
+		buf   bytes.Buffer
+		q     int
+		curQ  = -1
+		lines []int
+		ptrs  []*protocol.DocumentSymbol
+	)
+	header := fw.name + "\n"
+	buf.WriteString(header)
+	q += utf8.RuneCountInString(header)
+	walkDocumentSymbols(syms, 0, func(s *protocol.DocumentSymbol, depth int) {
+		mark := "  "
+		if s == cur {
+			mark = "» "
+			curQ = q
+		}
+		line := formatOutlineSymbol(s, depth, mark)
+		lines = append(lines, q)
+		ptrs = append(ptrs, s)
+		buf.WriteString(line)
+		q += utf8.RuneCountInString(line)
+	})
+
+	w.uri = pos.TextDocument.URI
+	w.syms = ptrs
+	w.lines = lines
+	w.Clear()
+	if _, err := w.Write("body", buf.Bytes()); err != nil {
+		return err
+	}
+	if curQ >= 0 {
+		w.Addr("#%d", curQ)
+	} else {
+		w.Addr("#0")
+	}
+	w.Ctl("dot=addr")
+	w.Ctl("show")
+	return w.Ctl("clean")
+}
+
+// formatOutlineSymbol returns the outline line of symbol s
+// at the given depth within the symbol tree.
+func formatOutlineSymbol(s *protocol.DocumentSymbol, depth int, mark string) string {
+	line := fmt.Sprintf("%v%v%v %v", mark, strings.Repeat("\t", depth), s.Kind, s.Name)
+	if s.Detail != "" {
+		line += " " + s.Detail
+	}
+	return strings.Replace(line, "\n", " ", -1) + "\n"
+}
+
+// symbolAt returns the symbol shown at rune offset q, or nil
+// if there is no symbol there (e.g. q is within the header).
+func (w *outlineWin) symbolAt(q int) *protocol.DocumentSymbol {
+	i := sort.Search(len(w.lines), func(i int) bool {
+		return w.lines[i] > q
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+	return w.syms[i]
+}
+
+// enclosingSymbols returns the symbols whose range contains pos,
+// from the outermost to the innermost one.
+func enclosingSymbols(syms []protocol.DocumentSymbol, pos protocol.Position) []*protocol.DocumentSymbol {
+	var path []*protocol.DocumentSymbol
+	for {
+		var next *protocol.DocumentSymbol
+		for i := range syms {
+			if rangeContains(syms[i].Range, pos) {
+				next = &syms[i]
+				break
+			}
+		}
+		if next == nil {
+			return path
+		}
+		path = append(path, next)
+		syms = next.Children
+	}
+}
+
+// formatBreadcrumb returns the names of the symbols separated by ›.
+func formatBreadcrumb(path []*protocol.DocumentSymbol) string {
+	names := make([]string, len(path))
+	for i, s := range path {
+		names[i] = s.Name
+	}
+	return strings.Join(names, " › ")
+}
+
+// rangeContains reports whether pos is within r, including its end.
+func rangeContains(r protocol.Range, pos protocol.Position) bool {
+	return !positionLess(pos, r.Start) && !positionLess(r.End, pos)
+}
+
+func positionLess(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
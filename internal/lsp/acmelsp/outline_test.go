@@ -0,0 +1,149 @@
+package acmelsp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func symbolRange(sl, sc, el, ec float64) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: sl, Character: sc},
+		End:   protocol.Position{Line: el, Character: ec},
+	}
+}
+
+func TestEnclosingSymbols(t *testing.T) {
+	syms := []protocol.DocumentSymbol{
+		{
+			Name:  "T",
+			Range: symbolRange(2, 0, 5, 1),
+			Children: []protocol.DocumentSymbol{
+				{Name: "a", Range: symbolRange(3, 1, 3, 7)},
+				{Name: "b", Range: symbolRange(4, 1, 4, 7)},
+			},
+		},
+		{Name: "main", Range: symbolRange(7, 0, 10, 1)},
+	}
+	for _, tc := range []struct {
+		line, col float64
+		want      []string
+	}{
+		{0, 0, nil},
+		{2, 0, []string{"T"}},
+		{4, 3, []string{"T", "b"}},
+		{5, 1, []string{"T"}},
+		{10, 1, []string{"main"}},
+		{10, 2, nil},
+	} {
+		var got []string
+		for _, s := range enclosingSymbols(syms, protocol.Position{Line: tc.line, Character: tc.col}) {
+			got = append(got, s.Name)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("symbols enclosing %v:%v are %q; want %q", tc.line, tc.col, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("symbols enclosing %v:%v are %q; want %q", tc.line, tc.col, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFormatOutlineSymbol(t *testing.T) {
+	s := &protocol.DocumentSymbol{
+		Name:   "Close",
+		Detail: "func() error",
+		Kind:   protocol.Method,
+	}
+	want := "» \tMethod Close func() error\n"
+	if got := formatOutlineSymbol(s, 1, "» "); got != want {
+		t.Errorf("formatted symbol is %q; want %q", got, want)
+	}
+}
+
+func TestOutlineWinSymbolAt(t *testing.T) {
+	syms := []protocol.DocumentSymbol{{Name: "T"}, {Name: "main"}}
+	w := &outlineWin{
+		syms:  []*protocol.DocumentSymbol{&syms[0], &syms[1]},
+		lines: []int{10, 20},
+	}
+	for _, tc := range []struct {
+		q    int
+		want string
+	}{
+		{0, ""},
+		{10, "T"},
+		{19, "T"},
+		{25, "main"},
+	} {
+		got := ""
+		if s := w.symbolAt(tc.q); s != nil {
+			got = s.Name
+		}
+		if got != tc.want {
+			t.Errorf("symbol at %v is %q; want %q", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestDocumentSymbolTree(t *testing.T) {
+	info := func(name, container string, rng protocol.Range) protocol.SymbolInformation {
+		return protocol.SymbolInformation{
+			Name:          name,
+			Kind:          protocol.Function,
+			Location:      protocol.Location{URI: "file:///a.py", Range: rng},
+			ContainerName: container,
+		}
+	}
+	ds := &protocol.DocumentSymbols{
+		Flat: []protocol.SymbolInformation{
+			info("method", "A", symbolRange(20, 4, 21, 0)), // defined before the container
+			info("A", "", symbolRange(1, 0, 10, 0)),
+			info("method", "A", symbolRange(2, 4, 3, 0)),
+			info("A", "", symbolRange(19, 0, 22, 0)),
+			info("helper", "method", symbolRange(2, 8, 2, 20)),
+			info("orphan", "missing", symbolRange(30, 0, 31, 0)),
+			info("loop", "loop", symbolRange(40, 0, 41, 0)),
+		},
+	}
+	var got []string
+	walkDocumentSymbols(documentSymbolTree(ds), 0, func(s *protocol.DocumentSymbol, depth int) {
+		got = append(got, fmt.Sprintf("%v%v@%v", strings.Repeat(" ", depth), s.Name, s.Range.Start.Line))
+	})
+	want := []string{
+		"A@1",
+		" method@2",
+		"  helper@2",
+		"A@19",
+		" method@20",
+		"orphan@30",
+		"loop@40",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("symbol tree is\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	hier := []protocol.DocumentSymbol{{Name: "T"}}
+	if got := documentSymbolTree(&protocol.DocumentSymbols{Hierarchical: hier}); !reflect.DeepEqual(got, hier) {
+		t.Errorf("hierarchical symbols changed to %v", got)
+	}
+}
+
+func TestFormatBreadcrumb(t *testing.T) {
+	syms := []protocol.DocumentSymbol{{Name: "Server"}, {Name: "Run"}, {Name: "func"}}
+	path := []*protocol.DocumentSymbol{&syms[0], &syms[1], &syms[2]}
+	want := "Server › Run › func"
+	if got := formatBreadcrumb(path); got != want {
+		t.Errorf("breadcrumb is %q; want %q", got, want)
+	}
+	if got := formatBreadcrumb(nil); got != "" {
+		t.Errorf("empty breadcrumb is %q", got)
+	}
+}
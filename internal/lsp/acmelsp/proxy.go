@@ -2,9 +2,11 @@ package acmelsp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
+	"github.com/tw4452852/acme-lsp/internal/lsp"
 	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
 	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
@@ -13,8 +15,11 @@ import (
 )
 
 type proxyServer struct {
-	ss *ServerSet // client connections to upstream LSP server (e.g. gopls)
-	fm *FileManager
+	ss       *ServerSet // client connections to upstream LSP server (e.g. gopls)
+	fm       *FileManager
+	tabStops *tabStopSet   // shared by all connections
+	jumps    *jumpList     // shared by all connections
+	results  *resultWinSet // shared by all connections
 }
 
 func (s *proxyServer) Version(ctx context.Context) (int, error) {
@@ -33,6 +38,85 @@ func (s *proxyServer) InitializeResult(ctx context.Context, params *protocol.Tex
 	return srv.Client.InitializeResult(ctx, params)
 }
 
+func (s *proxyServer) ResolveCompletionItemOnDocument(ctx context.Context, params *proxy.ResolveCompletionItemOnDocumentParams) (*protocol.CompletionItem, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("ResolveCompletionItemOnDocument: %v", err)
+	}
+	return srv.Client.Resolve(ctx, &params.CompletionItem)
+}
+
+func (s *proxyServer) SetTabStops(ctx context.Context, params *proxy.TabStopsParams) error {
+	s.tabStops.set(params.WinID, params.TabStops)
+	return nil
+}
+
+func (s *proxyServer) AddJump(ctx context.Context, params *proxy.AddJumpParams) error {
+	s.jumps.add(params.From, params.To)
+	return nil
+}
+
+func (s *proxyServer) Jump(ctx context.Context, params *proxy.JumpParams) (*protocol.Location, error) {
+	return s.jumps.move(params.Delta)
+}
+
+func (s *proxyServer) Jumps(ctx context.Context) (*proxy.JumpsResult, error) {
+	return s.jumps.list(), nil
+}
+
+func (s *proxyServer) ShowResults(ctx context.Context, params *proxy.ShowResultsParams) error {
+	rc := NewRemoteCmd(s, params.WinID)
+	rc.pos = &params.Position
+	return s.results.show(ctx, rc, params.Command)
+}
+
+func (s *proxyServer) Status(ctx context.Context) (*proxy.StatusResult, error) {
+	return s.ss.status(), nil
+}
+
+func (s *proxyServer) RestartServer(ctx context.Context, params *proxy.ServerParams) error {
+	return s.ss.Restart(ctx, params.Name)
+}
+
+func (s *proxyServer) StopServer(ctx context.Context, params *proxy.ServerParams) error {
+	return s.ss.Stop(ctx, params.Name)
+}
+
+func (s *proxyServer) Raw(ctx context.Context, params *proxy.RawParams) (json.RawMessage, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("Raw: %v", err)
+	}
+	return srv.Client.Raw(ctx, params)
+}
+
+func (s *proxyServer) NextTabStop(ctx context.Context, params *proxy.NextTabStopParams) (*text.TabStop, error) {
+	return s.tabStops.next(params.WinID, params.Q1)
+}
+
+// DidOpen opens a file that is not open in acme (e.g. for L -f).
+// If the language ID is empty, it's determined from the configuration
+// or the filename.
+func (s *proxyServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return fmt.Errorf("DidOpen: %v", err)
+	}
+	if params.TextDocument.LanguageID == "" {
+		name := text.ToPath(params.TextDocument.URI)
+		return lsp.DidOpen(ctx, srv.Client, name, srv.Client.cfg.FilenameHandler.LanguageID, []byte(params.TextDocument.Text))
+	}
+	return srv.Client.DidOpen(ctx, params)
+}
+
+func (s *proxyServer) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return fmt.Errorf("DidClose: %v", err)
+	}
+	return srv.Client.DidClose(ctx, params)
+}
+
 func (s *proxyServer) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
 	srv, err := serverForURI(s.ss, params.TextDocument.URI)
 	if err != nil {
@@ -77,6 +161,38 @@ func (s *proxyServer) CodeAction(ctx context.Context, params *protocol.CodeActio
 	return srv.Client.CodeAction(ctx, params)
 }
 
+func (s *proxyServer) CodeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("CodeLens: %v", err)
+	}
+	return srv.Client.CodeLens(ctx, params)
+}
+
+func (s *proxyServer) ResolveCodeLensOnDocument(ctx context.Context, params *proxy.ResolveCodeLensOnDocumentParams) (*protocol.CodeLens, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("ResolveCodeLensOnDocument: %v", err)
+	}
+	return srv.Client.ResolveCodeLens(ctx, &params.CodeLens)
+}
+
+func (s *proxyServer) DocumentLink(ctx context.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("DocumentLink: %v", err)
+	}
+	return srv.Client.DocumentLink(ctx, params)
+}
+
+func (s *proxyServer) ResolveDocumentLinkOnDocument(ctx context.Context, params *proxy.ResolveDocumentLinkOnDocumentParams) (*protocol.DocumentLink, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("ResolveDocumentLinkOnDocument: %v", err)
+	}
+	return srv.Client.ResolveDocumentLink(ctx, &params.DocumentLink)
+}
+
 func (s *proxyServer) ExecuteCommandOnDocument(ctx context.Context, params *proxy.ExecuteCommandOnDocumentParams) (interface{}, error) {
 	srv, err := serverForURI(s.ss, params.TextDocument.URI)
 	if err != nil {
@@ -101,6 +217,14 @@ func (s *proxyServer) Implementation(ctx context.Context, params *protocol.Imple
 	return srv.Client.Implementation(ctx, params)
 }
 
+func (s *proxyServer) InlayHint(ctx context.Context, params *protocol.InlayHintParams) ([]protocol.InlayHint, error) {
+	srv, err := serverForURI(s.ss, params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("InlayHint: %v", err)
+	}
+	return srv.Client.InlayHint(ctx, params)
+}
+
 func (s *proxyServer) References(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
 	srv, err := serverForURI(s.ss, params.TextDocumentPositionParams.TextDocument.URI)
 	if err != nil {
@@ -125,7 +249,7 @@ func (s *proxyServer) SignatureHelp(ctx context.Context, params *protocol.Signat
 	return srv.Client.SignatureHelp(ctx, params)
 }
 
-func (s *proxyServer) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error) {
+func (s *proxyServer) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) (*protocol.DocumentSymbols, error) {
 	srv, err := serverForURI(s.ss, params.TextDocument.URI)
 	if err != nil {
 		return nil, fmt.Errorf("DocumentSymbol: %v", err)
@@ -165,6 +289,9 @@ func ListenAndServeProxy(ctx context.Context, cfg *config.Config, ss *ServerSet,
 		<-ctx.Done()
 		ln.Close()
 	}()
+	tabStops := newTabStopSet()
+	jumps := &jumpList{}
+	results := newResultWinSet()
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
@@ -172,8 +299,11 @@ func ListenAndServeProxy(ctx context.Context, cfg *config.Config, ss *ServerSet,
 		}
 		stream := jsonrpc2.NewHeaderStream(conn, conn)
 		ctx, rpc, _ := proxy.NewServer(ctx, stream, &proxyServer{
-			ss: ss,
-			fm: fm,
+			ss:       ss,
+			fm:       fm,
+			tabStops: tabStops,
+			jumps:    jumps,
+			results:  results,
 		})
 		go rpc.Run(ctx)
 	}
@@ -0,0 +1,203 @@
+package acmelsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+// DefaultRawParams are the parameters of L raw
+// when none are given on the command line.
+const DefaultRawParams = `{"textDocument": $textDocument, "position": $position}`
+
+// expandRawParams replaces the placeholders in params with the JSON
+// encoding of the document URI ($uri), document identifier
+// ($textDocument), cursor position ($position), and selection
+// range ($selection), and checks that the result is valid JSON.
+// Placeholders inside JSON strings are not replaced, so "$uri"
+// is left as is instead of producing invalid JSON.
+func expandRawParams(params string, pos *protocol.TextDocumentPositionParams, sel *protocol.Range) (json.RawMessage, error) {
+	placeholders := make(map[string][]byte)
+	for _, p := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"$uri", pos.TextDocument.URI},
+		{"$textDocument", pos.TextDocument},
+		{"$position", pos.Position},
+		{"$selection", sel},
+	} {
+		b, err := json.Marshal(p.value)
+		if err != nil {
+			return nil, err
+		}
+		placeholders[p.name] = b
+	}
+
+	var b []byte
+	inString := false
+	for i := 0; i < len(params); i++ {
+		c := params[i]
+		switch {
+		case inString && c == '\\' && i+1 < len(params):
+			b = append(b, c)
+			i++
+			c = params[i]
+		case inString && c == '"':
+			inString = false
+		case c == '"':
+			inString = true
+		case !inString && c == '$':
+			j := i + 1
+			for j < len(params) && isLetter(params[j]) {
+				j++
+			}
+			if v, ok := placeholders[params[i:j]]; ok {
+				b = append(b, v...)
+				i = j - 1
+				continue
+			}
+		}
+		b = append(b, c)
+	}
+	if !json.Valid(b) {
+		return nil, fmt.Errorf("parameters are not valid JSON: %s", b)
+	}
+	return b, nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// Raw sends the LSP request (or notification, if notify is true) with
+// the given method and parameters to the server for the current file,
+// and prints the result as JSON. The parameters may contain the
+// placeholders described in expandRawParams.
+func (rc *RemoteCmd) Raw(ctx context.Context, method, params string, notify bool) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	pos, _, err := text.Position(f)
+	if err != nil {
+		return err
+	}
+	sel, err := text.Selection(f)
+	if err != nil {
+		return err
+	}
+	p, err := expandRawParams(params, pos, sel)
+	if err != nil {
+		return err
+	}
+	result, err := rc.server.Raw(ctx, &proxy.RawParams{
+		TextDocument: pos.TextDocument,
+		Method:       method,
+		Params:       p,
+		Notification: notify,
+	})
+	if err != nil {
+		return err
+	}
+	if notify {
+		return nil
+	}
+	return PrintJSON(rc.Stdout, result)
+}
+
+// Commands prints the commands that can be executed
+// by the LSP server for the current file.
+func (rc *RemoteCmd) Commands(ctx context.Context) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	uri, _, err := text.DocumentURI(f)
+	if err != nil {
+		return err
+	}
+	result, err := rc.server.InitializeResult(ctx, &protocol.TextDocumentIdentifier{
+		URI: uri,
+	})
+	if err != nil {
+		return err
+	}
+	cmds := []string{}
+	if p := result.Capabilities.ExecuteCommandProvider; p != nil {
+		cmds = append(cmds, p.Commands...)
+	}
+	if rc.JSON {
+		return rc.printJSON(cmds)
+	}
+	if len(cmds) == 0 {
+		fmt.Fprintf(rc.Stderr, "No commands found.\n")
+		return nil
+	}
+	for _, cmd := range cmds {
+		fmt.Fprintf(rc.Stdout, "%v\n", cmd)
+	}
+	return nil
+}
+
+// ExecuteCommand executes the command with the given arguments using the
+// LSP server for the current file. The arguments are a JSON array, which
+// may contain the placeholders described in expandRawParams. Any edits are
+// applied by acme-lsp when the server sends a workspace/applyEdit request
+// while executing the command.
+func (rc *RemoteCmd) ExecuteCommand(ctx context.Context, command, args string) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	pos, _, err := text.Position(f)
+	if err != nil {
+		return err
+	}
+	sel, err := text.Selection(f)
+	if err != nil {
+		return err
+	}
+	arguments, err := expandCommandArgs(args, pos, sel)
+	if err != nil {
+		return err
+	}
+	result, err := rc.server.ExecuteCommandOnDocument(ctx, &proxy.ExecuteCommandOnDocumentParams{
+		TextDocument: pos.TextDocument,
+		ExecuteCommandParams: protocol.ExecuteCommandParams{
+			Command:   command,
+			Arguments: arguments,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute %q: %v", command, err)
+	}
+	if rc.JSON {
+		return rc.printJSON(result)
+	}
+	return printCommandResult(rc.Stdout, result)
+}
+
+// expandCommandArgs expands the placeholders in the JSON array args
+// (see expandRawParams) and returns the command arguments.
+func expandCommandArgs(args string, pos *protocol.TextDocumentPositionParams, sel *protocol.Range) ([]interface{}, error) {
+	b, err := expandRawParams(args, pos, sel)
+	if err != nil {
+		return nil, err
+	}
+	var arguments []interface{}
+	if err := json.Unmarshal(b, &arguments); err != nil {
+		return nil, fmt.Errorf("arguments are not a JSON array: %v", err)
+	}
+	return arguments, nil
+}
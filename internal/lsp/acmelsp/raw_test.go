@@ -0,0 +1,113 @@
+package acmelsp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func TestExpandRawParams(t *testing.T) {
+	pos := &protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: "file:///home/gopher/main.go",
+		},
+		Position: protocol.Position{Line: 3, Character: 5},
+	}
+	sel := &protocol.Range{
+		Start: protocol.Position{Line: 3, Character: 5},
+		End:   protocol.Position{Line: 4, Character: 0},
+	}
+	for _, tc := range []struct {
+		params, want string
+	}{
+		{
+			DefaultRawParams,
+			`{"textDocument": {"uri":"file:///home/gopher/main.go"}, "position": {"line":3,"character":5}}`,
+		},
+		{
+			`{"uri": $uri, "range": $selection}`,
+			`{"uri": "file:///home/gopher/main.go", "range": {"start":{"line":3,"character":5},"end":{"line":4,"character":0}}}`,
+		},
+		{
+			`[]`,
+			`[]`,
+		},
+		{
+			`{"text": "$uri is \"$position\"", "uri": $uri}`,
+			`{"text": "$uri is \"$position\"", "uri": "file:///home/gopher/main.go"}`,
+		},
+		{
+			`[$selection,$position]`,
+			`[{"start":{"line":3,"character":5},"end":{"line":4,"character":0}},{"line":3,"character":5}]`,
+		},
+	} {
+		got, err := expandRawParams(tc.params, pos, sel)
+		if err != nil {
+			t.Fatalf("expandRawParams(%q) failed: %v", tc.params, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("expandRawParams(%q) is %s; want %s", tc.params, got, tc.want)
+		}
+	}
+	for _, params := range []string{`{"uri": `, `{"uri": $url}`} {
+		if _, err := expandRawParams(params, pos, sel); err == nil {
+			t.Errorf("expandRawParams(%q) succeeded for invalid JSON", params)
+		}
+	}
+}
+
+func TestExpandCommandArgs(t *testing.T) {
+	pos := &protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: "file:///home/gopher/main.go",
+		},
+		Position: protocol.Position{Line: 3, Character: 5},
+	}
+	sel := &protocol.Range{
+		Start: protocol.Position{Line: 3, Character: 5},
+		End:   protocol.Position{Line: 4, Character: 0},
+	}
+	for _, tc := range []struct {
+		args string
+		want []interface{}
+		ok   bool
+	}{
+		{`[]`, []interface{}{}, true},
+		{
+			`[{"URIs": [$uri]}]`,
+			[]interface{}{
+				map[string]interface{}{
+					"URIs": []interface{}{"file:///home/gopher/main.go"},
+				},
+			},
+			true,
+		},
+		{
+			`["$uri", $position]`,
+			[]interface{}{
+				"$uri",
+				map[string]interface{}{"line": 3.0, "character": 5.0},
+			},
+			true,
+		},
+		{`{"URIs": [$uri]}`, nil, false},
+		{`$uri`, nil, false},
+		{`[$uri`, nil, false},
+	} {
+		got, err := expandCommandArgs(tc.args, pos, sel)
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("expandCommandArgs(%q) is %v; want error", tc.args, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandCommandArgs(%q) failed: %v", tc.args, err)
+			continue
+		}
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("expandCommandArgs(%q) is %v; want %v", tc.args, got, tc.want)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package acmelsp
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/tw4452852/acme-lsp/internal/acmeutil"
 	"github.com/tw4452852/acme-lsp/internal/lsp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/acmelsp/config"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
 	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
 	"github.com/tw4452852/acme-lsp/internal/lsp/text"
@@ -18,8 +25,28 @@ import (
 type RemoteCmd struct {
 	server proxy.Server
 	winid  int
+	file   *headlessFile                        // if non-nil, used instead of the window
+	pos    *protocol.TextDocumentPositionParams // if nil, cursor position of the window
 	Stdout io.Writer
 	Stderr io.Writer
+
+	// JSON causes results (e.g. locations, symbols) to be printed to Stdout
+	// as JSON instead of human-readable text. Definition and TypeDefinition
+	// print the locations instead of opening them, and Rename prints the
+	// workspace edit instead of applying it.
+	JSON bool
+
+	// Diff causes edits to files made by a RemoteCmd returned by
+	// FileRemoteCmd to be printed as a unified diff instead.
+	Diff bool
+
+	// CompletionMatcher is the algorithm used to filter completion
+	// candidates. See config.File.CompletionMatcher for possible values.
+	CompletionMatcher string
+
+	// LocationOpener determines how locations are opened in acme.
+	// See config.File.LocationOpener for possible values.
+	LocationOpener string
 }
 
 func NewRemoteCmd(server proxy.Server, winid int) *RemoteCmd {
@@ -28,20 +55,44 @@ func NewRemoteCmd(server proxy.Server, winid int) *RemoteCmd {
 		winid:  winid,
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
+
+		CompletionMatcher: config.CaseInsensitiveMatcher,
+		LocationOpener:    config.AutoOpener,
+	}
+}
+
+// printJSON prints v to Stdout as JSON.
+func (rc *RemoteCmd) printJSON(v interface{}) error {
+	return PrintJSON(rc.Stdout, v)
+}
+
+// PrintJSON writes v to w as indented JSON.
+func PrintJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
 	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
 }
 
 func (rc *RemoteCmd) getPosition() (pos *protocol.TextDocumentPositionParams, filename string, err error) {
-	w, err := acmeutil.OpenWin(rc.winid)
+	if rc.pos != nil {
+		return rc.pos, text.ToPath(rc.pos.TextDocument.URI), nil
+	}
+	f, err := rc.openFile()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to to open window %v: %v", rc.winid, err)
+		return nil, "", err
 	}
-	defer w.CloseFiles()
+	defer f.CloseFiles()
 
-	return text.Position(w)
+	return text.Position(f)
 }
 
 func (rc *RemoteCmd) DidChange(ctx context.Context) error {
+	if rc.file != nil {
+		return rc.syncFile(ctx)
+	}
 	w, err := acmeutil.OpenWin(rc.winid)
 	if err != nil {
 		return fmt.Errorf("failed to to open window %v: %v", rc.winid, err)
@@ -82,6 +133,14 @@ func generateEditCmd(te protocol.TextEdit) string {
 }
 
 func (rc *RemoteCmd) Completion(ctx context.Context, edit bool) error {
+	return rc.completion(ctx, edit, &protocol.CompletionContext{
+		TriggerKind: protocol.Invoked,
+	})
+}
+
+// completion is like Completion but it sends the given context
+// describing how completion was triggered.
+func (rc *RemoteCmd) completion(ctx context.Context, edit bool, cc *protocol.CompletionContext) error {
 	w, err := acmeutil.OpenWin(rc.winid)
 	if err != nil {
 		return err
@@ -93,53 +152,37 @@ func (rc *RemoteCmd) Completion(ctx context.Context, edit bool) error {
 		return err
 	}
 	result, err := rc.server.Completion(ctx, &protocol.CompletionParams{
+		Context:                    cc,
 		TextDocumentPositionParams: *pos,
 	})
 	if err != nil {
 		return err
 	}
-	if edit && len(result.Items) == 1 {
-		textEdit := result.Items[0].TextEdit
-		if textEdit == nil {
-			// TODO(fhs): Use insertText or label instead.
-			return fmt.Errorf("nil TextEdit in completion item")
-		}
-		if err := text.Edit(w, []protocol.TextEdit{*textEdit}); err != nil {
-			return fmt.Errorf("failed to apply completion edit: %v", err)
-		}
-		return nil
+	items, err := matchingCompletionItems(w, pos.Position, result.Items, rc.CompletionMatcher)
+	if err != nil {
+		return err
 	}
-	if len(result.Items) == 0 {
+	if edit && len(items) == 1 {
+		rc.resolveCompletionItems(ctx, pos.TextDocument, items, 1)
+		return rc.applyCompletionItem(ctx, w, &items[0])
+	}
+	if len(items) == 0 && !rc.JSON {
 		fmt.Fprintf(rc.Stderr, "no completion\n")
 	}
-	var selection string
-	for _, item := range result.Items {
-		te := item.TextEdit
-		if te == nil {
-			continue
-		}
-		sl, sc, el, ec := int(te.Range.Start.Line), int(te.Range.Start.Character), int(te.Range.End.Line), int(te.Range.End.Character)
-		if selection == "" {
-			var buf = make([]byte, ec - sc)
-			_, err := w.ReadAt(sl, sc, el, ec, buf);
-			if err != nil {
-				continue
-			}
-			selection = string(buf)
-		}
-
-		filter := te.NewText
-		if item.InsertTextFormat == 2 {
-			filter = item.FilterText
+	rc.resolveCompletionItems(ctx, pos.TextDocument, items, maxResolvedCompletionItems)
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.CompletionItem{}, items...))
+	}
+	for _, item := range items {
+		fmt.Fprintf(rc.Stdout, "%s", formatCompletionItem(&item))
+		for _, ate := range item.AdditionalTextEdits {
+			fmt.Fprintf(rc.Stdout, "%s\n", generateEditCmd(ate))
 		}
-
-		if selection != "" && strings.HasPrefix(strings.ToLower(filter), strings.ToLower(selection)) {
-			fmt.Fprintf(rc.Stdout, "%q, type: %d\n", item.Label, item.Kind)
-			for _, ate := range item.AdditionalTextEdits {
-				fmt.Fprintf(rc.Stdout, "%s\n", generateEditCmd(ate))
-			}
-			fmt.Fprintf(rc.Stdout, "%s\n", generateEditCmd(*te))
+		te := *item.TextEdit
+		if item.InsertTextFormat == protocol.SnippetTextFormat {
+			te.NewText = text.ParseSnippet(te.NewText).Text
 		}
+		fmt.Fprintf(rc.Stdout, "%s\n", generateEditCmd(te))
 	}
 	return nil
 }
@@ -155,10 +198,34 @@ func (rc *RemoteCmd) Definition(ctx context.Context, print bool) error {
 	if err != nil {
 		return fmt.Errorf("bad server response: %v", err)
 	}
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.Location{}, locations...))
+	}
 	if print {
 		return PrintLocations(rc.Stdout, locations)
 	}
-	return PlumbLocations(locations)
+	return rc.openJump(ctx, pos, locations)
+}
+
+// openJump opens the locations and records the jump from the
+// cursor position to the first location in the navigation history.
+func (rc *RemoteCmd) openJump(ctx context.Context, pos *protocol.TextDocumentPositionParams, locations []protocol.Location) error {
+	if err := OpenLocations(locations, rc.LocationOpener); err != nil {
+		return err
+	}
+	if len(locations) == 0 {
+		return nil
+	}
+	return rc.server.AddJump(ctx, &proxy.AddJumpParams{
+		From: protocol.Location{
+			URI: pos.TextDocument.URI,
+			Range: protocol.Range{
+				Start: pos.Position,
+				End:   pos.Position,
+			},
+		},
+		To: locations[0],
+	})
 }
 
 func (rc *RemoteCmd) OrganizeImportsAndFormat(ctx context.Context) error {
@@ -192,7 +259,10 @@ func (rc *RemoteCmd) Hover(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(rc.Stdout, "%v\n", hov.Contents.Value)
+	if rc.JSON {
+		return rc.printJSON(hov)
+	}
+	fmt.Fprintf(rc.Stdout, "%v\n", lsp.MarkupText(&hov.Contents))
 	return nil
 }
 
@@ -207,6 +277,9 @@ func (rc *RemoteCmd) Implementation(ctx context.Context, print bool) error {
 	if err != nil {
 		return err
 	}
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.Location{}, loc...))
+	}
 	if len(loc) == 0 {
 		fmt.Fprintf(rc.Stderr, "No implementations found.\n")
 		return nil
@@ -214,6 +287,78 @@ func (rc *RemoteCmd) Implementation(ctx context.Context, print bool) error {
 	return PrintLocations(rc.Stdout, loc)
 }
 
+// inlayHintLines is the number of lines above and below the cursor
+// for which inlay hints are shown. Acme doesn't expose the visible
+// range of a window, so we approximate it.
+const inlayHintLines = 40
+
+// inlayHintRange returns the range of lines around the given line
+// for which inlay hints are shown. The range ends at the end of body
+// if there are less than inlayHintLines lines below the line.
+func inlayHintRange(body []byte, line int) protocol.Range {
+	start := line - inlayHintLines
+	if start < 0 {
+		start = 0
+	}
+	end := protocol.Position{Line: float64(line + inlayHintLines + 1)}
+	if nlines := bytes.Count(body, []byte("\n")); int(end.Line) > nlines {
+		last := body[bytes.LastIndexByte(body, '\n')+1:]
+		end = protocol.Position{
+			Line:      float64(nlines),
+			Character: float64(utf8.RuneCount(last)),
+		}
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: float64(start)},
+		End:   end,
+	}
+}
+
+// InlayHints prints the inlay hints for lines surrounding the cursor
+// position as "line:col label" entries.
+func (rc *RemoteCmd) InlayHints(ctx context.Context) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	pos, _, err := text.Position(f)
+	if err != nil {
+		return err
+	}
+	rd, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	hints, err := rc.server.InlayHint(ctx, &protocol.InlayHintParams{
+		TextDocument: pos.TextDocument,
+		Range:        inlayHintRange(body, int(pos.Position.Line)),
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(hints, func(i, j int) bool {
+		a := hints[i].Position
+		b := hints[j].Position
+		if a.Line == b.Line {
+			return a.Character < b.Character
+		}
+		return a.Line < b.Line
+	})
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.InlayHint{}, hints...))
+	}
+	for _, h := range hints {
+		fmt.Fprintf(rc.Stdout, "%v:%v %v\n", h.Position.Line+1, h.Position.Character+1, h.Label)
+	}
+	return nil
+}
+
 func (rc *RemoteCmd) References(ctx context.Context) error {
 	pos, _, err := rc.getPosition()
 	if err != nil {
@@ -228,6 +373,9 @@ func (rc *RemoteCmd) References(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.Location{}, loc...))
+	}
 	if len(loc) == 0 {
 		fmt.Fprintf(rc.Stderr, "No references found.\n")
 		return nil
@@ -249,47 +397,98 @@ func (rc *RemoteCmd) Rename(ctx context.Context, newname string) error {
 	if err != nil {
 		return err
 	}
-	return editWorkspace(we)
+	if rc.JSON {
+		return rc.printJSON(we)
+	}
+	return rc.editWorkspace(we)
 }
 
 func (rc *RemoteCmd) SignatureHelp(ctx context.Context) error {
+	_, err := rc.signatureHelp(ctx, &protocol.SignatureHelpContext{
+		TriggerKind: protocol.SigInvoked,
+	})
+	return err
+}
+
+// signatureHelp is like SignatureHelp but it sends the given context
+// describing how signature help was triggered. It returns the
+// signature help printed.
+func (rc *RemoteCmd) signatureHelp(ctx context.Context, shc *protocol.SignatureHelpContext) (*protocol.SignatureHelp, error) {
 	pos, _, err := rc.getPosition()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	sh, err := rc.server.SignatureHelp(ctx, &protocol.SignatureHelpParams{
+		Context:                    shc,
 		TextDocumentPositionParams: *pos,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, sig := range sh.Signatures {
-		fmt.Fprintf(rc.Stdout, "%v\n", sig.Label)
-		fmt.Fprintf(rc.Stdout, "%v\n", sig.Documentation)
+	if rc.JSON {
+		return sh, rc.printJSON(sh)
 	}
-	return nil
+	fmt.Fprintf(rc.Stdout, "%s", formatSignatureHelp(sh))
+	return sh, nil
 }
 
-func (rc *RemoteCmd) DocumentSymbol(ctx context.Context) error {
-	win, err := acmeutil.OpenWin(rc.winid)
-	if err != nil {
-		return err
+// formatSignatureHelp formats the signatures with the active one first.
+// The active parameter is surrounded by «», and the documentation of the
+// signature and its parameters are indented below it.
+func formatSignatureHelp(sh *protocol.SignatureHelp) string {
+	if len(sh.Signatures) == 0 {
+		return ""
+	}
+	active := int(sh.ActiveSignature)
+	if active < 0 || active >= len(sh.Signatures) {
+		active = 0
+	}
+	order := []int{active}
+	for i := range sh.Signatures {
+		if i != active {
+			order = append(order, i)
+		}
 	}
-	defer win.CloseFiles()
 
-	uri, _, err := text.DocumentURI(win)
+	var b strings.Builder
+	for n, i := range order {
+		sig := &sh.Signatures[i]
+		if n > 0 {
+			b.WriteString("\n")
+		}
+
+		label := sig.Label
+		if i == active {
+			if p := int(sh.ActiveParameter); p >= 0 && p < len(sig.Parameters) {
+				if _, start, end, ok := sig.Parameters[p].Label.In(label); ok {
+					label = label[:start] + "«" + label[start:end] + "»" + label[end:]
+				}
+			}
+		}
+		fmt.Fprintf(&b, "%v\n", label)
+		if doc := strings.TrimSpace(lsp.MarkupText(sig.Documentation)); doc != "" {
+			fmt.Fprintf(&b, "\t%v\n", strings.Replace(doc, "\n", "\n\t", -1))
+		}
+		for _, param := range sig.Parameters {
+			doc := strings.TrimSpace(lsp.MarkupText(param.Documentation))
+			if doc == "" {
+				continue
+			}
+			name, _, _, _ := param.Label.In(sig.Label)
+			fmt.Fprintf(&b, "\t%v: %v\n", name, strings.Replace(doc, "\n", "\n\t\t", -1))
+		}
+	}
+	return b.String()
+}
+
+func (rc *RemoteCmd) DocumentSymbol(ctx context.Context) error {
+	pos, _, err := rc.getPosition()
 	if err != nil {
 		return err
 	}
+	uri := pos.TextDocument.URI
 
-	// TODO(fhs): DocumentSymbol request can return either a
-	// []DocumentSymbol (hierarchical) or []SymbolInformation (flat).
-	// We only handle the hierarchical type below.
-
-	// TODO(fhs): Make use of DocumentSymbol.Range to optionally filter out
-	// symbols that aren't within current cursor position?
-
-	syms, err := rc.server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+	result, err := rc.server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
 		TextDocument: protocol.TextDocumentIdentifier{
 			URI: uri,
 		},
@@ -297,6 +496,10 @@ func (rc *RemoteCmd) DocumentSymbol(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	syms := documentSymbolTree(result)
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.DocumentSymbol{}, syms...))
+	}
 	if len(syms) == 0 {
 		fmt.Fprintf(rc.Stderr, "No symbols found.\n")
 		return nil
@@ -313,6 +516,300 @@ func (rc *RemoteCmd) DocumentSymbol(ctx context.Context) error {
 	return nil
 }
 
+// Where prints the path of symbols enclosing the cursor position
+// (e.g. "Type › Method"), followed by the location of each symbol.
+func (rc *RemoteCmd) Where(ctx context.Context) error {
+	uri, path, err := rc.enclosingSymbols(ctx)
+	if err != nil {
+		return err
+	}
+	if rc.JSON {
+		infos := []protocol.SymbolInformation{}
+		for i, s := range path {
+			info := protocol.SymbolInformation{
+				Name:       s.Name,
+				Kind:       s.Kind,
+				Deprecated: s.Deprecated,
+				Location: protocol.Location{
+					URI:   uri,
+					Range: s.SelectionRange,
+				},
+			}
+			if i > 0 {
+				info.ContainerName = path[i-1].Name
+			}
+			infos = append(infos, info)
+		}
+		return rc.printJSON(infos)
+	}
+	if len(path) == 0 {
+		fmt.Fprintf(rc.Stderr, "No enclosing symbols found.\n")
+		return nil
+	}
+	fmt.Fprintf(rc.Stdout, "%v\n", formatBreadcrumb(path))
+	for _, s := range path {
+		loc := &protocol.Location{
+			URI:   uri,
+			Range: s.SelectionRange,
+		}
+		fmt.Fprintf(rc.Stdout, "%v: %v %v\n", lsp.LocationLink(loc), s.Kind, s.Name)
+	}
+	return nil
+}
+
+// enclosingSymbols returns the document URI of the window and the
+// symbols enclosing the cursor position, from the outermost one.
+func (rc *RemoteCmd) enclosingSymbols(ctx context.Context) (protocol.DocumentURI, []*protocol.DocumentSymbol, error) {
+	pos, _, err := rc.getPosition()
+	if err != nil {
+		return "", nil, err
+	}
+	result, err := rc.server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+		TextDocument: pos.TextDocument,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return pos.TextDocument.URI, enclosingSymbols(documentSymbolTree(result), pos.Position), nil
+}
+
+// CodeLens lists the code lenses of the current window. If index is
+// non-negative, the code lens at that index is resolved and its
+// command is executed instead.
+func (rc *RemoteCmd) CodeLens(ctx context.Context, index int) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	uri, _, err := text.DocumentURI(f)
+	if err != nil {
+		return err
+	}
+	doc := protocol.TextDocumentIdentifier{
+		URI: uri,
+	}
+	lenses, err := rc.server.CodeLens(ctx, &protocol.CodeLensParams{
+		TextDocument: doc,
+	})
+	if err != nil {
+		return err
+	}
+	sortCodeLenses(lenses)
+
+	// A code lens which fails to resolve is listed
+	// unresolved, and its error is reported.
+	if index < 0 && rc.JSON {
+		for i := range lenses {
+			cmd, err := rc.codeLensCommand(ctx, doc, &lenses[i])
+			if err != nil {
+				fmt.Fprintf(rc.Stderr, "%v\n", err)
+				continue
+			}
+			lenses[i].Command = cmd
+		}
+		return rc.printJSON(append([]protocol.CodeLens{}, lenses...))
+	}
+	if index < 0 {
+		if len(lenses) == 0 {
+			fmt.Fprintf(rc.Stderr, "No code lenses found.\n")
+			return nil
+		}
+		for i, l := range lenses {
+			loc := &protocol.Location{
+				URI:   uri,
+				Range: l.Range,
+			}
+			cmd, err := rc.codeLensCommand(ctx, doc, &l)
+			if err != nil {
+				fmt.Fprintf(rc.Stdout, "%v: [%v] (unresolved)\n", lsp.LocationLink(loc), i)
+				fmt.Fprintf(rc.Stderr, "code lens %v: %v\n", i, err)
+				continue
+			}
+			fmt.Fprintf(rc.Stdout, "%v: [%v] %v\n", lsp.LocationLink(loc), i, cmd.Title)
+		}
+		return nil
+	}
+	if index >= len(lenses) {
+		return fmt.Errorf("code lens %v not found (there are %v code lenses)", index, len(lenses))
+	}
+	cmd, err := rc.codeLensCommand(ctx, doc, &lenses[index])
+	if err != nil {
+		return err
+	}
+	// Any edits are applied by acme-lsp when the server sends
+	// a workspace/applyEdit request while executing the command.
+	result, err := rc.server.ExecuteCommandOnDocument(ctx, &proxy.ExecuteCommandOnDocumentParams{
+		TextDocument: doc,
+		ExecuteCommandParams: protocol.ExecuteCommandParams{
+			Command:   cmd.Command,
+			Arguments: cmd.Arguments,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute %q: %v", cmd.Command, err)
+	}
+	if rc.JSON {
+		return rc.printJSON(result)
+	}
+	return printCommandResult(rc.Stdout, result)
+}
+
+// codeLensCommand returns the command of the code lens l,
+// resolving the code lens if necessary.
+func (rc *RemoteCmd) codeLensCommand(ctx context.Context, doc protocol.TextDocumentIdentifier, l *protocol.CodeLens) (*protocol.Command, error) {
+	if l.Command != nil {
+		return l.Command, nil
+	}
+	resolved, err := rc.server.ResolveCodeLensOnDocument(ctx, &proxy.ResolveCodeLensOnDocumentParams{
+		TextDocument: doc,
+		CodeLens:     *l,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve code lens: %v", err)
+	}
+	if resolved.Command == nil {
+		return nil, fmt.Errorf("code lens at %v has no command", lsp.LocationLink(&protocol.Location{
+			URI:   doc.URI,
+			Range: l.Range,
+		}))
+	}
+	return resolved.Command, nil
+}
+
+func sortCodeLenses(lenses []protocol.CodeLens) {
+	sort.SliceStable(lenses, func(i, j int) bool {
+		a := lenses[i].Range.Start
+		b := lenses[j].Range.Start
+		if a.Line == b.Line {
+			return a.Character < b.Character
+		}
+		return a.Line < b.Line
+	})
+}
+
+// printCommandResult prints the result of a workspace/executeCommand
+// request, if there is one.
+func printCommandResult(w io.Writer, result interface{}) error {
+	switch v := result.(type) {
+	case nil:
+		return nil
+	case string:
+		fmt.Fprintf(w, "%v\n", v)
+		return nil
+	}
+	return PrintJSON(w, result)
+}
+
+// DocumentLink lists the links in the current window. Each link is
+// printed with its plumbable target. A link which fails to resolve
+// is listed unresolved, and its error is reported.
+func (rc *RemoteCmd) DocumentLink(ctx context.Context) error {
+	f, err := rc.openFile()
+	if err != nil {
+		return err
+	}
+	defer f.CloseFiles()
+
+	uri, _, err := text.DocumentURI(f)
+	if err != nil {
+		return err
+	}
+	doc := protocol.TextDocumentIdentifier{
+		URI: uri,
+	}
+	links, err := rc.server.DocumentLink(ctx, &protocol.DocumentLinkParams{
+		TextDocument: doc,
+	})
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 && !rc.JSON {
+		fmt.Fprintf(rc.Stderr, "No links found.\n")
+		return nil
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		a := links[i].Range.Start
+		b := links[j].Range.Start
+		if a.Line == b.Line {
+			return a.Character < b.Character
+		}
+		return a.Line < b.Line
+	})
+	resolved := []protocol.DocumentLink{}
+	unresolved := make(map[int]bool) // indices into resolved
+	for _, l := range links {
+		if l.Target == "" {
+			r, err := rc.server.ResolveDocumentLinkOnDocument(ctx, &proxy.ResolveDocumentLinkOnDocumentParams{
+				TextDocument: doc,
+				DocumentLink: l,
+			})
+			if err != nil {
+				loc := &protocol.Location{
+					URI:   uri,
+					Range: l.Range,
+				}
+				fmt.Fprintf(rc.Stderr, "document link %v: %v\n", lsp.LocationLink(loc), err)
+				unresolved[len(resolved)] = true
+				resolved = append(resolved, l)
+				continue
+			}
+			l = *r
+		}
+		if l.Target != "" {
+			resolved = append(resolved, l)
+		}
+	}
+	if rc.JSON {
+		return rc.printJSON(resolved)
+	}
+	for i, l := range resolved {
+		loc := &protocol.Location{
+			URI:   uri,
+			Range: l.Range,
+		}
+		if unresolved[i] {
+			fmt.Fprintf(rc.Stdout, "%v: (unresolved)\n", lsp.LocationLink(loc))
+			continue
+		}
+		fmt.Fprintf(rc.Stdout, "%v: %v\n", lsp.LocationLink(loc), documentLinkTarget(l.Target))
+	}
+	return nil
+}
+
+// documentLinkTarget converts the target of a document link to
+// something that can be plumbed. File targets are converted to
+// a location link, where a fragment like "#L10" or "#L10,5"
+// selects the line and column. Other targets (e.g. http URLs)
+// are returned unchanged.
+func documentLinkTarget(target string) string {
+	if !strings.HasPrefix(target, "file://") {
+		return target
+	}
+	var pos protocol.Position
+	if i := strings.Index(target, "#"); i >= 0 {
+		frag := strings.TrimPrefix(target[i+1:], "L")
+		target = target[:i]
+		f := strings.SplitN(frag, ",", 2)
+		if line, err := strconv.Atoi(f[0]); err == nil && line > 0 {
+			pos.Line = float64(line - 1)
+			if len(f) == 2 {
+				if col, err := strconv.Atoi(f[1]); err == nil && col > 0 {
+					pos.Character = float64(col - 1)
+				}
+			}
+		}
+	}
+	return lsp.LocationLink(&protocol.Location{
+		URI: protocol.DocumentURI(target),
+		Range: protocol.Range{
+			Start: pos,
+			End:   pos,
+		},
+	})
+}
+
 func (rc *RemoteCmd) TypeDefinition(ctx context.Context, print bool) error {
 	pos, _, err := rc.getPosition()
 	if err != nil {
@@ -324,15 +821,89 @@ func (rc *RemoteCmd) TypeDefinition(ctx context.Context, print bool) error {
 	if err != nil {
 		return err
 	}
+	if rc.JSON {
+		return rc.printJSON(append([]protocol.Location{}, locations...))
+	}
 	if print {
 		return PrintLocations(rc.Stdout, locations)
 	}
-	return PlumbLocations(locations)
+	return rc.openJump(ctx, pos, locations)
+}
+
+// documentSymbolTree returns the hierarchy of document symbols. If the
+// server returned flat symbols, each one is nested within the symbol
+// named by its containerName, preferring a container enclosing it.
+func documentSymbolTree(ds *protocol.DocumentSymbols) []protocol.DocumentSymbol {
+	if len(ds.Flat) == 0 {
+		return ds.Hierarchical
+	}
+
+	type node struct {
+		sym      protocol.DocumentSymbol
+		parent   *node
+		children []*node
+	}
+	nodes := make([]*node, len(ds.Flat))
+	for i, si := range ds.Flat {
+		nodes[i] = &node{
+			sym: protocol.DocumentSymbol{
+				Name:           si.Name,
+				Kind:           si.Kind,
+				Deprecated:     si.Deprecated,
+				Range:          si.Location.Range,
+				SelectionRange: si.Location.Range,
+			},
+		}
+	}
+	isAncestor := func(a, n *node) bool {
+		for ; n != nil; n = n.parent {
+			if n == a {
+				return true
+			}
+		}
+		return false
+	}
+	var roots []*node
+	for i, si := range ds.Flat {
+		n := nodes[i]
+		var parent *node
+		for _, p := range nodes {
+			if si.ContainerName == "" || p.sym.Name != si.ContainerName || isAncestor(n, p) {
+				continue
+			}
+			if rangeContains(p.sym.Range, n.sym.Range.Start) {
+				parent = p
+				break
+			}
+			if parent == nil {
+				parent = p
+			}
+		}
+		if parent == nil {
+			roots = append(roots, n)
+			continue
+		}
+		n.parent = parent
+		parent.children = append(parent.children, n)
+	}
+
+	var build func(nodes []*node) []protocol.DocumentSymbol
+	build = func(nodes []*node) []protocol.DocumentSymbol {
+		var syms []protocol.DocumentSymbol
+		for _, n := range nodes {
+			s := n.sym
+			s.Children = build(n.children)
+			syms = append(syms, s)
+		}
+		return syms
+	}
+	return build(roots)
 }
 
 func walkDocumentSymbols(syms []protocol.DocumentSymbol, depth int, f func(s *protocol.DocumentSymbol, depth int)) {
-	for _, s := range syms {
-		f(&s, depth)
+	for i := range syms {
+		s := &syms[i]
+		f(s, depth)
 		walkDocumentSymbols(s.Children, depth+1, f)
 	}
 }
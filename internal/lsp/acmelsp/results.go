@@ -0,0 +1,185 @@
+package acmelsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tw4452852/acme-lsp/internal/acmeutil"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+)
+
+// resultCommands are the L commands whose output can be shown
+// in a result window instead of being printed to stdout.
+var resultCommands = []string{"hov", "impls", "refs", "status", "syms"}
+
+// runResultCommand runs the L command cmd, one of resultCommands.
+func runResultCommand(ctx context.Context, rc *RemoteCmd, cmd string) error {
+	switch cmd {
+	case "hov":
+		return rc.Hover(ctx)
+	case "impls":
+		return rc.Implementation(ctx, true)
+	case "refs":
+		return rc.References(ctx)
+	case "status":
+		return rc.Status(ctx)
+	case "syms":
+		return rc.DocumentSymbol(ctx)
+	}
+	return fmt.Errorf("unknown result command %q", cmd)
+}
+
+// resultWin is an acme window showing the output of an L command.
+// Executing Reload in its tag runs the command again.
+type resultWin struct {
+	name string // window name
+	*acmeutil.Win
+	rc  *RemoteCmd // command context, including the original position
+	cmd string     // L command (e.g. "refs")
+
+	mu sync.Mutex
+}
+
+// reload runs the command and replaces the body of the window with its output.
+func (rw *resultWin) reload(ctx context.Context) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	// The file may have been edited since the last run.
+	// The status command doesn't depend on a file.
+	if rw.cmd != "status" {
+		if err := rw.rc.DidChange(ctx); err != nil {
+			dprintf("%v: DidChange failed: %v\n", rw.name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	rc := *rw.rc
+	rc.Stdout = &buf
+	rc.Stderr = &buf
+	if err := runResultCommand(ctx, &rc, rw.cmd); err != nil {
+		fmt.Fprintf(&buf, "%v\n", err)
+	}
+
+	rw.Clear()
+	if _, err := rw.Write("body", buf.Bytes()); err != nil {
+		return err
+	}
+	rw.Addr("#0")
+	rw.Ctl("dot=addr")
+	rw.Ctl("show")
+	return rw.Ctl("clean")
+}
+
+// resultWinSet maps window name to result windows that are open.
+type resultWinSet struct {
+	m  map[string]*resultWin
+	mu sync.Mutex
+}
+
+func newResultWinSet() *resultWinSet {
+	return &resultWinSet{
+		m: make(map[string]*resultWin),
+	}
+}
+
+// show runs the L command cmd and writes its output to the result
+// window of the command, creating the window if necessary.
+func (s *resultWinSet) show(ctx context.Context, rc *RemoteCmd, cmd string) error {
+	if !isResultCommand(cmd) {
+		return fmt.Errorf("unknown result command %q", cmd)
+	}
+	name := "/LSP/" + cmd
+	if cmd == "status" {
+		name = "/LSP/Status"
+	}
+	rw, err := s.open(name)
+	if err != nil {
+		return fmt.Errorf("failed to create acme window: %v", err)
+	}
+	rw.mu.Lock()
+	rw.rc = rc
+	rw.cmd = cmd
+	rw.mu.Unlock()
+	return rw.reload(ctx)
+}
+
+// open returns the result window with the given name. If it's not open,
+// a window with that name is hijacked or a new window is created.
+func (s *resultWinSet) open(name string) (*resultWin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rw, ok := s.m[name]; ok {
+		return rw, nil
+	}
+	w, err := acmeutil.Hijack(name)
+	if err != nil {
+		w, err = acmeutil.NewWin()
+		if err != nil {
+			return nil, err
+		}
+		w.Name(name)
+		w.Write("tag", []byte("Reload "))
+	}
+	rw := &resultWin{
+		name: name,
+		Win:  w,
+	}
+	s.m[name] = rw
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.m, name)
+			s.mu.Unlock()
+			rw.Del(true)
+			rw.CloseFiles()
+		}()
+
+		for ev := range rw.EventChan() {
+			if ev == nil {
+				return
+			}
+			switch ev.C2 {
+			case 'x', 'X': // execute
+				switch string(ev.Text) {
+				case "Del":
+					return
+				case "Reload":
+					if err := rw.reload(context.Background()); err != nil {
+						dprintf("%v: reload failed: %v\n", name, err)
+					}
+					continue
+				}
+			}
+			rw.WriteEvent(ev)
+		}
+	}()
+	return rw, nil
+}
+
+func isResultCommand(cmd string) bool {
+	for _, c := range resultCommands {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// ShowResults asks the proxy server to run the L command cmd at the
+// cursor position and show its output in a result window.
+func (rc *RemoteCmd) ShowResults(ctx context.Context, cmd string) error {
+	pos, _, err := rc.getPosition()
+	if err != nil {
+		return err
+	}
+	return rc.server.ShowResults(ctx, &proxy.ShowResultsParams{
+		Command:  cmd,
+		WinID:    rc.winid,
+		Position: *pos,
+	})
+}
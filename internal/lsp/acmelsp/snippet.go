@@ -0,0 +1,85 @@
+package acmelsp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+// tabStopList is the list of tab stops of a snippet inserted
+// into an acme window.
+type tabStopList struct {
+	cur  text.TabStop   // currently selected tab stop
+	rest []text.TabStop // tab stops not yet visited
+}
+
+// next moves to the next tab stop, given that the current tab stop
+// has been edited and now ends at rune offset q. Tab stops following
+// the current one are shifted by the change in length of the current
+// tab stop, and tab stops within it are dropped if it was changed.
+func (l *tabStopList) next(q int) (text.TabStop, bool) {
+	shift := q - l.cur.Q1
+	var rest []text.TabStop
+	for _, ts := range l.rest {
+		inside := l.cur.Q0 <= ts.Q0 && ts.Q0 < l.cur.Q1 && ts.Q1 <= l.cur.Q1
+		if inside && shift != 0 {
+			continue
+		}
+		if ts.Q0 >= l.cur.Q1 {
+			ts.Q0 += shift
+			ts.Q1 += shift
+		}
+		rest = append(rest, ts)
+	}
+	if len(rest) == 0 {
+		l.rest = nil
+		return text.TabStop{}, false
+	}
+	l.cur, l.rest = rest[0], rest[1:]
+	return l.cur, true
+}
+
+// tabStopSet maps acme window ID to the tab stops within the window.
+type tabStopSet struct {
+	m  map[int]*tabStopList
+	mu sync.Mutex
+}
+
+func newTabStopSet() *tabStopSet {
+	return &tabStopSet{
+		m: make(map[int]*tabStopList),
+	}
+}
+
+func (s *tabStopSet) set(winid int, stops []text.TabStop) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(stops) < 2 {
+		delete(s.m, winid)
+		return
+	}
+	s.m[winid] = &tabStopList{
+		cur:  stops[0],
+		rest: stops[1:],
+	}
+}
+
+func (s *tabStopSet) next(winid int, q int) (*text.TabStop, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.m[winid]
+	if !ok {
+		return nil, fmt.Errorf("no tab stops in window %v", winid)
+	}
+	ts, ok := l.next(q)
+	if len(l.rest) == 0 {
+		delete(s.m, winid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("no more tab stops in window %v", winid)
+	}
+	return &ts, nil
+}
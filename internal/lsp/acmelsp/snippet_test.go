@@ -0,0 +1,66 @@
+package acmelsp
+
+import (
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+func tabStop(q0, q1 int) text.TabStop {
+	return text.TabStop{Q0: q0, Q1: q1}
+}
+
+func TestTabStopSet(t *testing.T) {
+	const winid = 42
+
+	// Text inserted at offset 100 from snippet:
+	//	Printf(${1:format string}, ${2:a ...interface{\}})$0
+	s := newTabStopSet()
+	s.set(winid, []text.TabStop{tabStop(107, 120), tabStop(122, 138), tabStop(139, 139)})
+
+	// Replace "format string" with `"%v\n"`.
+	ts, err := s.next(winid, 113)
+	if err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if want := tabStop(115, 131); *ts != want {
+		t.Errorf("second tab stop is %v; want %v", *ts, want)
+	}
+
+	// Leave "a ...interface{}" as is.
+	ts, err = s.next(winid, 131)
+	if err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	if want := tabStop(132, 132); *ts != want {
+		t.Errorf("final tab stop is %v; want %v", *ts, want)
+	}
+
+	if _, err := s.next(winid, 132); err == nil {
+		t.Errorf("next succeeded after the final tab stop")
+	}
+}
+
+func TestTabStopListNested(t *testing.T) {
+	// ${1:foo ${2:bar}}$0
+	l := &tabStopList{
+		cur:  tabStop(0, 7),
+		rest: []text.TabStop{tabStop(4, 7), tabStop(7, 7)},
+	}
+	ts, ok := l.next(1) // replaced "foo bar" with "x"
+	if !ok {
+		t.Fatalf("no tab stop after replacing nested placeholder")
+	}
+	if want := tabStop(1, 1); ts != want {
+		t.Errorf("next tab stop is %v; want %v", ts, want)
+	}
+
+	l = &tabStopList{
+		cur:  tabStop(0, 7),
+		rest: []text.TabStop{tabStop(4, 7), tabStop(7, 7)},
+	}
+	ts, ok = l.next(7) // unchanged
+	if want := tabStop(4, 7); !ok || ts != want {
+		t.Errorf("next tab stop is %v; want %v", ts, want)
+	}
+}
@@ -0,0 +1,155 @@
+package acmelsp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
+)
+
+// status returns the status of the servers in the set.
+func (ss *ServerSet) status() *proxy.StatusResult {
+	result := &proxy.StatusResult{
+		Workspaces: append([]protocol.WorkspaceFolder{}, ss.Workspaces()...),
+		Servers:    []proxy.ServerStatus{},
+	}
+	for _, info := range ss.Data {
+		st := proxy.ServerStatus{
+			Pattern:      info.Re.String(),
+			Capabilities: []string{},
+			Documents:    []proxy.DocumentStatus{},
+		}
+		// See ServerInfo.start.
+		if len(info.Address) > 0 {
+			st.Address = info.Address
+		} else {
+			st.Command = info.Command
+		}
+		if srv := info.running(); srv != nil {
+			st.Running = true
+			srv.mu.Lock()
+			st.PID = srv.pid
+			st.Started = srv.started
+			st.Restarts = srv.restarts
+			if srv.exitErr != nil {
+				st.ExitError = srv.exitErr.Error()
+			}
+			srv.mu.Unlock()
+
+			st.Capabilities = capabilityNames(&srv.Client.initResult().Capabilities)
+			for uri, doc := range srv.Client.documents() {
+				st.Documents = append(st.Documents, proxy.DocumentStatus{
+					URI:     uri,
+					Version: doc.version,
+				})
+			}
+			sort.Slice(st.Documents, func(i, j int) bool {
+				return st.Documents[i].URI < st.Documents[j].URI
+			})
+		}
+		result.Servers = append(result.Servers, st)
+	}
+	return result
+}
+
+// capabilityNames returns the names of the features supported
+// by a server with the given capabilities.
+func capabilityNames(c *protocol.ServerCapabilities) []string {
+	names := []string{}
+	for _, p := range []struct {
+		name     string
+		provided bool
+	}{
+		{"completion", c.CompletionProvider != nil},
+		{"hover", c.HoverProvider},
+		{"signatureHelp", c.SignatureHelpProvider != nil},
+		{"declaration", c.DeclarationProvider},
+		{"definition", c.DefinitionProvider},
+		{"typeDefinition", c.TypeDefinitionProvider},
+		{"implementation", c.ImplementationProvider},
+		{"references", c.ReferencesProvider},
+		{"documentHighlight", c.DocumentHighlightProvider},
+		{"documentSymbol", c.DocumentSymbolProvider},
+		{"codeAction", provided(c.CodeActionProvider)},
+		{"codeLens", c.CodeLensProvider != nil},
+		{"documentLink", c.DocumentLinkProvider != nil},
+		{"color", provided(c.ColorProvider)},
+		{"workspaceSymbol", c.WorkspaceSymbolProvider},
+		{"formatting", c.DocumentFormattingProvider},
+		{"rangeFormatting", c.DocumentRangeFormattingProvider},
+		{"onTypeFormatting", c.DocumentOnTypeFormattingProvider != nil},
+		{"rename", provided(c.RenameProvider)},
+		{"foldingRange", c.FoldingRangeProvider},
+		{"selectionRange", c.SelectionRangeProvider},
+		{"inlayHint", provided(c.InlayHintProvider)},
+		{"executeCommand", c.ExecuteCommandProvider != nil},
+	} {
+		if p.provided {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// provided returns true if the provider, which can be
+// a boolean or an options struct, is provided.
+func provided(v interface{}) bool {
+	b, ok := v.(bool)
+	return v != nil && (!ok || b)
+}
+
+// PrintStatus writes the status of the servers in a human-readable form.
+func PrintStatus(w io.Writer, status *proxy.StatusResult) {
+	printStatus(w, status, time.Now())
+}
+
+func printStatus(w io.Writer, status *proxy.StatusResult, now time.Time) {
+	fmt.Fprintf(w, "Workspace folders:\n")
+	for _, d := range status.Workspaces {
+		fmt.Fprintf(w, "\t%v\n", text.ToPath(d.URI))
+	}
+	for _, st := range status.Servers {
+		server := st.Address
+		if server == "" {
+			server = strings.Join(st.Command, " ")
+		}
+		fmt.Fprintf(w, "\n%v: %v\n", st.Pattern, server)
+		if !st.Running {
+			fmt.Fprintf(w, "\tnot started\n")
+			continue
+		}
+		if st.PID > 0 {
+			fmt.Fprintf(w, "\tpid %v, ", st.PID)
+		} else {
+			fmt.Fprintf(w, "\t")
+		}
+		fmt.Fprintf(w, "up %v, %v restarts\n", now.Sub(st.Started).Round(time.Second), st.Restarts)
+		if st.ExitError != "" {
+			fmt.Fprintf(w, "\tlast exit: %v\n", st.ExitError)
+		}
+		fmt.Fprintf(w, "\tcapabilities: %v\n", strings.Join(st.Capabilities, " "))
+		fmt.Fprintf(w, "\tdocuments:\n")
+		for _, doc := range st.Documents {
+			fmt.Fprintf(w, "\t\t%v (version %v)\n", text.ToPath(doc.URI), doc.Version)
+		}
+	}
+}
+
+// Status prints the status of the LSP servers.
+func (rc *RemoteCmd) Status(ctx context.Context) error {
+	status, err := rc.server.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if rc.JSON {
+		return rc.printJSON(status)
+	}
+	PrintStatus(rc.Stdout, status)
+	return nil
+}
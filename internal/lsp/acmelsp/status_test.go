@@ -0,0 +1,139 @@
+package acmelsp
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/proxy"
+)
+
+// versionServer records the document versions sent by the client.
+type versionServer struct {
+	protocol.Server
+	versions []float64
+}
+
+func (s *versionServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	s.versions = append(s.versions, params.TextDocument.Version)
+	return nil
+}
+
+func (s *versionServer) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	s.versions = append(s.versions, params.TextDocument.Version)
+	return nil
+}
+
+func (s *versionServer) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	return nil
+}
+
+func TestClientDocumentVersions(t *testing.T) {
+	ctx := context.Background()
+	srv := &versionServer{}
+	c := &Client{
+		Server: srv,
+		docs:   make(map[protocol.DocumentURI]*document),
+	}
+	uri := protocol.DocumentURI("file:///home/gopher/main.go")
+	change := func(version float64) {
+		err := c.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				Version:                version,
+			},
+		})
+		if err != nil {
+			t.Fatalf("DidChange failed: %v", err)
+		}
+	}
+
+	err := c.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen failed: %v", err)
+	}
+	change(0)
+	change(0)
+	change(10)
+	change(3)
+	if want := []float64{0, 1, 2, 10, 11}; !cmp.Equal(srv.versions, want) {
+		t.Errorf("versions are %v; want %v", srv.versions, want)
+	}
+	if v := c.documents()[uri].version; v != 11 {
+		t.Errorf("document version is %v; want 11", v)
+	}
+
+	err = c.DidClose(ctx, &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DidClose failed: %v", err)
+	}
+	if docs := c.documents(); len(docs) != 0 {
+		t.Errorf("documents after close are %v; want none", docs)
+	}
+}
+
+func TestCapabilityNames(t *testing.T) {
+	names := capabilityNames(&protocol.ServerCapabilities{
+		HoverProvider:      true,
+		CodeActionProvider: false,
+		RenameProvider:     map[string]interface{}{"prepareProvider": true},
+		InlayHintProvider:  true,
+		CodeLensProvider:   &protocol.CodeLensOptions{},
+	})
+	if want := []string{"hover", "codeLens", "rename", "inlayHint"}; !cmp.Equal(names, want) {
+		t.Errorf("capability names are %v; want %v", names, want)
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	status := &proxy.StatusResult{
+		Workspaces: []protocol.WorkspaceFolder{
+			{URI: "file:///home/gopher/mod", Name: "/home/gopher/mod"},
+		},
+		Servers: []proxy.ServerStatus{
+			{
+				Pattern:      `\.go$`,
+				Command:      []string{"gopls", "serve"},
+				Running:      true,
+				PID:          42,
+				Started:      now.Add(-90 * time.Second),
+				Restarts:     1,
+				ExitError:    "signal: killed",
+				Capabilities: []string{"hover", "definition"},
+				Documents: []proxy.DocumentStatus{
+					{URI: "file:///home/gopher/mod/main.go", Version: 3},
+				},
+			},
+			{
+				Pattern: `\.py$`,
+				Address: "localhost:4389",
+			},
+		},
+	}
+	var buf bytes.Buffer
+	printStatus(&buf, status, now)
+	want := `Workspace folders:
+	/home/gopher/mod
+
+\.go$: gopls serve
+	pid 42, up 1m30s, 1 restarts
+	last exit: signal: killed
+	capabilities: hover definition
+	documents:
+		/home/gopher/mod/main.go (version 3)
+
+\.py$: localhost:4389
+	not started
+`
+	if got := buf.String(); got != want {
+		t.Errorf("status is\n%v\nwant\n%v", got, want)
+	}
+}
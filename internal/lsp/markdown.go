@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+// MarkupText returns the content as plain text suitable for
+// a fixed-width acme window. Markdown is rendered by RenderMarkdown.
+func MarkupText(mc *protocol.MarkupContent) string {
+	if mc == nil {
+		return ""
+	}
+	if mc.Kind == protocol.Markdown {
+		return RenderMarkdown(mc.Value)
+	}
+	return mc.Value
+}
+
+// RenderMarkdown converts markdown to readable plain text. Code blocks are
+// kept as is without their fences, links are written as "text <url>" so
+// that the URL can be plumbed, and markup such as headings, emphasis,
+// code spans and backslash escapes are removed.
+func RenderMarkdown(s string) string {
+	var (
+		b     strings.Builder
+		fence string // fence of the code block we're in
+	)
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+				continue
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+			continue
+		}
+		if f := codeFence(trimmed); f != "" {
+			fence = f
+			continue
+		}
+		if isHeading(trimmed) {
+			line = strings.TrimSpace(strings.TrimRight(strings.TrimLeft(trimmed, "#"), "#"))
+		}
+		if strings.HasSuffix(line, "\\") { // hard line break
+			line = line[:len(line)-1]
+		}
+		b.WriteString(renderInline(strings.TrimRight(line, " ")))
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// codeFence returns the fence (e.g. "```") if line opens
+// a fenced code block. Otherwise, it returns "".
+func codeFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+func isHeading(line string) bool {
+	n := len(line) - len(strings.TrimLeft(line, "#"))
+	return n >= 1 && n <= 6 && (len(line) == n || line[n] == ' ')
+}
+
+// renderInline removes inline markup from a line of markdown text.
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			i++
+			b.WriteByte(s[i])
+
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			delim := s[i : i+n]
+			end := strings.Index(s[i+n:], delim)
+			if end < 0 {
+				b.WriteString(delim)
+				i += n - 1
+				break
+			}
+			code := s[i+n : i+n+end]
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString(code)
+			i += n + end + n - 1
+
+		case c == '*' && strings.HasPrefix(s[i:], "**"),
+			c == '_' && strings.HasPrefix(s[i:], "__"):
+			n := strongEmphasis(s, i)
+			if n == 0 {
+				b.WriteString(s[i : i+2])
+				i++
+				break
+			}
+			b.WriteString(renderInline(s[i+2 : i+n-2]))
+			i += n - 1
+
+		case c == '[' || c == '!' && strings.HasPrefix(s[i:], "!["):
+			start := i
+			if c == '!' {
+				start++
+			}
+			text, url, n := parseLink(s[start:])
+			if n == 0 {
+				b.WriteByte(c)
+				break
+			}
+			text = renderInline(text)
+			if text == "" || text == url {
+				b.WriteString("<" + url + ">")
+			} else {
+				b.WriteString(text + " <" + url + ">")
+			}
+			i = start + n - 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// strongEmphasis returns the length of the strong emphasis (e.g.
+// "**text**") starting at s[i], or 0 if the delimiter at s[i] isn't
+// matched. Delimiters must be at word boundaries, so that a**b and
+// **kwargs are kept as is. Text within "__" can't be a single identifier,
+// so that Python names like __init__ are kept too.
+func strongEmphasis(s string, i int) int {
+	delim := s[i : i+2]
+	if i > 0 && isWordChar(s[i-1]) || i+2 >= len(s) || s[i+2] == ' ' {
+		return 0
+	}
+	for j := i + 3; j+2 <= len(s); j++ {
+		if s[j:j+2] != delim || s[j-1] == ' ' || j+2 < len(s) && isWordChar(s[j+2]) {
+			continue
+		}
+		if delim == "__" && strings.IndexFunc(s[i+2:j], func(r rune) bool {
+			return r >= 0x80 || !isWordChar(byte(r))
+		}) < 0 {
+			return 0
+		}
+		return j + 2 - i
+	}
+	return 0
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// parseLink parses an inline link of the form "[text](url "title")"
+// at the beginning of s. It returns the link text, its destination,
+// and the length of the link. The length is 0 if there is no link.
+func parseLink(s string) (text, url string, n int) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0
+			}
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 {
+				return "", "", 0
+			}
+			dest := strings.TrimSpace(s[i+2 : i+2+end])
+			if j := strings.IndexAny(dest, " \t"); j >= 0 {
+				dest = dest[:j] // drop the title
+			}
+			dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+			return s[1:i], dest, i + 2 + end + 1
+		}
+	}
+	return "", "", 0
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
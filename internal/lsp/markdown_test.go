@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	for _, tc := range []struct {
+		name, markdown, want string
+	}{
+		{
+			"gopls hover",
+			"```go\nfunc fmt.Println(a ...any) (n int, err error)\n```\n\nPrintln formats using the default formats for its operands and writes to standard output\\.\n\n\n[`fmt.Println` on pkg.go.dev](https://pkg.go.dev/fmt#Println)",
+			"func fmt.Println(a ...any) (n int, err error)\n\nPrintln formats using the default formats for its operands and writes to standard output.\n\n\nfmt.Println on pkg.go.dev <https://pkg.go.dev/fmt#Println>",
+		},
+		{
+			"code block keeps markup",
+			"~~~\nx := a[i]\\*2 // **not bold**\n~~~~\nafter",
+			"x := a[i]\\*2 // **not bold**\nafter",
+		},
+		{
+			"heading and emphasis",
+			"## Usage ##\nCall **Run** with `ctx`\\_1.",
+			"Usage\nCall Run with ctx_1.",
+		},
+		{
+			"unmatched emphasis delimiters",
+			"def __init__(self, *args, **kwargs): return a**b",
+			"def __init__(self, *args, **kwargs): return a**b",
+		},
+		{
+			"emphasis",
+			"**strong** and __also strong__, not __init__ or **a**b",
+			"strong and also strong, not __init__ or **a**b",
+		},
+		{
+			"links",
+			"See [the spec](https://go.dev/ref/spec \"Spec\"), ![logo](https://go.dev/logo.png) and [https://go.dev](https://go.dev).",
+			"See the spec <https://go.dev/ref/spec>, logo <https://go.dev/logo.png> and <https://go.dev>.",
+		},
+		{
+			"not links",
+			"a[i] and [x] (y) and `unterminated",
+			"a[i] and [x] (y) and `unterminated",
+		},
+		{
+			"code span with backticks",
+			"Use `` a`b `` here",
+			"Use a`b here",
+		},
+		{
+			"hard line break",
+			"first\\\nsecond  \nthird",
+			"first\nsecond\nthird",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := RenderMarkdown(tc.markdown)
+			if got != tc.want {
+				t.Errorf("rendered markdown is %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMarkupText(t *testing.T) {
+	for _, tc := range []struct {
+		mc   protocol.MarkupContent
+		want string
+	}{
+		{protocol.MarkupContent{Kind: protocol.PlainText, Value: "a\\_b"}, "a\\_b"},
+		{protocol.MarkupContent{Kind: protocol.Markdown, Value: "a\\_b"}, "a_b"},
+	} {
+		if got := MarkupText(&tc.mc); got != tc.want {
+			t.Errorf("text of %v content %q is %q; want %q", tc.mc.Kind, tc.mc.Value, got, tc.want)
+		}
+	}
+}
@@ -2,12 +2,24 @@ package protocol
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
+	"unicode/utf16"
 )
 
 // tsprotocol.go is not general enough to support other LSP servers besides
 // gopls. Let's try to be compatible with all LSP servers.
 
+// SignatureHelpTriggerKind values missing from tsprotocol.go because
+// their names clash with CompletionTriggerKind values.
+const (
+	// SigInvoked means signature help was invoked manually by the user or by a command.
+	SigInvoked SignatureHelpTriggerKind = 1
+
+	// SigTriggerCharacter means signature help was triggered by a trigger character.
+	SigTriggerCharacter SignatureHelpTriggerKind = 2
+)
+
 func (m *MarkupContent) UnmarshalJSON(data []byte) error {
 	d := strings.TrimSpace(string(data))
 	if len(d) == 0 {
@@ -55,6 +67,19 @@ type CodeActionLiteralSupport = struct {
 	} `json:"codeActionKind"`
 }
 
+// SignatureInformationCapabilities is a type alias that works around difficulty in initializing the pointer
+// SignatureHelpClientCapabilities.SignatureInformation.
+type SignatureInformationCapabilities = struct {
+	DocumentationFormat  []MarkupKind                      `json:"documentationFormat,omitempty"`
+	ParameterInformation *ParameterInformationCapabilities `json:"parameterInformation,omitempty"`
+}
+
+// ParameterInformationCapabilities is a type alias that works around difficulty in initializing the pointer
+// SignatureHelpClientCapabilities.SignatureInformation.ParameterInformation.
+type ParameterInformationCapabilities = struct {
+	LabelOffsetSupport bool `json:"labelOffsetSupport,omitempty"`
+}
+
 func ToCodeActionOptions(v map[string]interface{}) (*CodeActionOptions, error) {
 	b, err := json.Marshal(v)
 	if err != nil {
@@ -95,3 +120,105 @@ func (ls *Locations) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// ParameterLabel is the label of a ParameterInformation, which is either
+// a string or a pair of offsets within the label of the signature.
+type ParameterLabel struct {
+	// Label is the parameter label, if it's given as a string.
+	Label string
+
+	// Offsets contains the inclusive start and exclusive end UTF-16
+	// offsets of the parameter label within the signature label, if
+	// the label is given as offsets. It's nil otherwise.
+	Offsets []uint32
+}
+
+func (pl *ParameterLabel) UnmarshalJSON(data []byte) error {
+	d := strings.TrimSpace(string(data))
+	if len(d) > 0 && d[0] == '[' {
+		var offsets []uint32
+		if err := json.Unmarshal(data, &offsets); err != nil {
+			return err
+		}
+		if len(offsets) != 2 {
+			return fmt.Errorf("parameter label has %v offsets; want 2", len(offsets))
+		}
+		*pl = ParameterLabel{Offsets: offsets}
+		return nil
+	}
+	*pl = ParameterLabel{}
+	return json.Unmarshal(data, &pl.Label)
+}
+
+func (pl ParameterLabel) MarshalJSON() ([]byte, error) {
+	if pl.Offsets != nil {
+		return json.Marshal(pl.Offsets)
+	}
+	return json.Marshal(pl.Label)
+}
+
+// In returns the parameter label and its byte offsets within the label
+// of signature. It returns false if the parameter isn't found there.
+func (pl *ParameterLabel) In(signature string) (label string, start, end int, ok bool) {
+	if pl.Offsets != nil {
+		u := utf16.Encode([]rune(signature))
+		s, e := int(pl.Offsets[0]), int(pl.Offsets[1])
+		if s > e || e > len(u) {
+			return "", 0, 0, false
+		}
+		start = len(string(utf16.Decode(u[:s])))
+		end = start + len(string(utf16.Decode(u[s:e])))
+		return signature[start:end], start, end, true
+	}
+	if pl.Label == "" {
+		return "", 0, 0, false
+	}
+	// Skip the function name, which may contain the parameter label.
+	off := strings.Index(signature, "(") + 1
+	i := strings.Index(signature[off:], pl.Label)
+	if i < 0 {
+		return pl.Label, 0, 0, false
+	}
+	start = off + i
+	return pl.Label, start, start + len(pl.Label), true
+}
+
+// DocumentSymbols is the result of a textDocument/documentSymbol request,
+// which is either hierarchical ([]DocumentSymbol) or flat ([]SymbolInformation).
+// At most one of the fields is non-empty.
+type DocumentSymbols struct {
+	Hierarchical []DocumentSymbol
+	Flat         []SymbolInformation
+}
+
+func (ds *DocumentSymbols) UnmarshalJSON(data []byte) error {
+	*ds = DocumentSymbols{}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	// Only SymbolInformation has a location.
+	var probe struct {
+		Location *json.RawMessage `json:"location"`
+	}
+	if err := json.Unmarshal(items[0], &probe); err != nil {
+		return err
+	}
+	if probe.Location != nil {
+		return json.Unmarshal(data, &ds.Flat)
+	}
+	return json.Unmarshal(data, &ds.Hierarchical)
+}
+
+func (ds DocumentSymbols) MarshalJSON() ([]byte, error) {
+	if ds.Flat != nil {
+		return json.Marshal(ds.Flat)
+	}
+	if ds.Hierarchical == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(ds.Hierarchical)
+}
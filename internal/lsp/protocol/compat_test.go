@@ -279,3 +279,189 @@ func TestToCodeActionOptions(t *testing.T) {
 		t.Errorf("got %#v; want %#v", got, want)
 	}
 }
+
+func TestInlayHintLabel(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want InlayHintLabel
+		str  string
+	}{
+		{
+			data: []byte(`"n:"`),
+			want: InlayHintLabel{{Value: "n:"}},
+			str:  "n:",
+		},
+		{
+			data: []byte(`[{"value":"[]"},{"value":"byte","location":{"uri":"file:///builtin.go","range":{"start":{"line":1,"character":5},"end":{"line":1,"character":9}}}}]`),
+			want: InlayHintLabel{
+				{Value: "[]"},
+				{
+					Value: "byte",
+					Location: &Location{
+						URI: "file:///builtin.go",
+						Range: Range{
+							Start: Position{Line: 1, Character: 5},
+							End:   Position{Line: 1, Character: 9},
+						},
+					},
+				},
+			},
+			str: "[]byte",
+		},
+	}
+	for _, test := range tests {
+		var got InlayHintLabel
+		if err := json.Unmarshal(test.data, &got); err != nil {
+			t.Errorf("json.Unmarshal %q error: %v", test.data, err)
+			continue
+		}
+		if !cmp.Equal(got, test.want) {
+			t.Errorf("Unmarshaled %q, expected %#v, but got %#v", test.data, test.want, got)
+		}
+		if got, want := got.String(), test.str; got != want {
+			t.Errorf("label %q is %q; want %q", test.data, got, want)
+		}
+	}
+}
+
+func TestParameterLabel(t *testing.T) {
+	const signature = "Fprintf(w io.Writer, format string, a ...interface{}) (n int, err error)"
+	tests := []struct {
+		data  []byte
+		want  ParameterLabel
+		label string
+		ok    bool
+	}{
+		{
+			data:  []byte(`"format string"`),
+			want:  ParameterLabel{Label: "format string"},
+			label: "format string",
+			ok:    true,
+		},
+		{
+			data:  []byte(`[21,34]`),
+			want:  ParameterLabel{Offsets: []uint32{21, 34}},
+			label: "format string",
+			ok:    true,
+		},
+		{
+			data:  []byte(`"x int"`),
+			want:  ParameterLabel{Label: "x int"},
+			label: "x int",
+			ok:    false,
+		},
+		{
+			data: []byte(`[21,100]`),
+			want: ParameterLabel{Offsets: []uint32{21, 100}},
+			ok:   false,
+		},
+	}
+	for _, test := range tests {
+		var got ParameterLabel
+		if err := json.Unmarshal(test.data, &got); err != nil {
+			t.Errorf("json.Unmarshal %q error: %v", test.data, err)
+			continue
+		}
+		if !cmp.Equal(got, test.want) {
+			t.Errorf("Unmarshaled %q, expected %#v, but got %#v", test.data, test.want, got)
+		}
+		data, err := json.Marshal(got)
+		if err != nil {
+			t.Errorf("json.Marshal %#v error: %v", got, err)
+		} else if !bytes.Equal(data, test.data) {
+			t.Errorf("Marshaled %#v is %q; want %q", got, data, test.data)
+		}
+		label, start, end, ok := got.In(signature)
+		if label != test.label || ok != test.ok {
+			t.Errorf("parameter %q in signature is %q, %v; want %q, %v", test.data, label, ok, test.label, test.ok)
+		}
+		if ok && signature[start:end] != label {
+			t.Errorf("parameter %q is at %v:%v in signature, which is %q", test.data, start, end, signature[start:end])
+		}
+	}
+	pl := &ParameterLabel{Offsets: []uint32{2, 4}}
+	if label, _, _, ok := pl.In("é(aé)"); !ok || label != "aé" {
+		t.Errorf("UTF-16 offsets %v select %q, %v; want %q, true", pl.Offsets, label, ok, "aé")
+	}
+}
+
+func TestSignatureInformation(t *testing.T) {
+	data := []byte(`{"label":"f(x int)","parameters":[{"label":"x int"}]}`)
+	var sig SignatureInformation
+	if err := json.Unmarshal(data, &sig); err != nil {
+		t.Fatalf("json.Unmarshal %q error: %v", data, err)
+	}
+	if sig.Documentation != nil || sig.Parameters[0].Documentation != nil {
+		t.Errorf("Unmarshaled %q has documentation %#v", data, sig)
+	}
+	got, err := json.Marshal(sig)
+	if err != nil {
+		t.Fatalf("json.Marshal %#v error: %v", sig, err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Marshaled %#v is %s; want %s", sig, got, data)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	rng := Range{
+		Start: Position{Line: 1, Character: 5},
+		End:   Position{Line: 1, Character: 9},
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want DocumentSymbols
+	}{
+		{
+			name: "Empty",
+			data: []byte(`[]`),
+			want: DocumentSymbols{},
+		},
+		{
+			name: "Hierarchical",
+			data: []byte(`[{"name":"T","kind":23,"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":9}},"selectionRange":{"start":{"line":1,"character":5},"end":{"line":1,"character":9}}}]`),
+			want: DocumentSymbols{
+				Hierarchical: []DocumentSymbol{
+					{Name: "T", Kind: Struct, Range: rng, SelectionRange: rng},
+				},
+			},
+		},
+		{
+			name: "Flat",
+			data: []byte(`[{"name":"f","kind":6,"location":{"uri":"file:///a.go","range":{"start":{"line":1,"character":5},"end":{"line":1,"character":9}}},"containerName":"T"}]`),
+			want: DocumentSymbols{
+				Flat: []SymbolInformation{
+					{
+						Name:          "f",
+						Kind:          Method,
+						Location:      Location{URI: "file:///a.go", Range: rng},
+						ContainerName: "T",
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got DocumentSymbols
+			if err := json.Unmarshal(test.data, &got); err != nil {
+				t.Fatalf("json.Unmarshal error: %v", err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("unmarshaled %#v; want %#v", got, test.want)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("json.Marshal error: %v", err)
+			}
+			var again DocumentSymbols
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatalf("json.Unmarshal of %q error: %v", data, err)
+			}
+			if !cmp.Equal(again, test.want) {
+				t.Errorf("round trip through %q gives %#v; want %#v", data, again, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Inlay hints were added in LSP 3.17.0, which is newer than the
+// specification tsprotocol.go was generated from.
+
+/*InlayHintClientCapabilities defined:
+ * Inlay hint client capabilities.
+ *
+ * @since 3.17.0
+ */
+type InlayHintClientCapabilities struct {
+
+	/*DynamicRegistration defined:
+	 * Whether inlay hints support dynamic registration.
+	 */
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+/*InlayHintParams defined:
+ * A parameter literal used in inlay hint requests.
+ *
+ * @since 3.17.0
+ */
+type InlayHintParams struct {
+
+	/*TextDocument defined:
+	 * The text document.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/*Range defined:
+	 * The document range for which inlay hints should be computed.
+	 */
+	Range Range `json:"range"`
+	WorkDoneProgressParams
+}
+
+/*InlayHint defined:
+ * Inlay hint information.
+ *
+ * @since 3.17.0
+ */
+type InlayHint struct {
+
+	/*Position defined:
+	 * The position of this hint.
+	 */
+	Position Position `json:"position"`
+
+	/*Label defined:
+	 * The label of this hint. A human readable string or an array of
+	 * InlayHintLabelPart label parts.
+	 */
+	Label InlayHintLabel `json:"label"`
+
+	/*Kind defined:
+	 * The kind of this hint. Can be omitted in which case the client
+	 * should fall back to a reasonable default.
+	 */
+	Kind InlayHintKind `json:"kind,omitempty"`
+
+	/*TextEdits defined:
+	 * Optional text edits that are performed when accepting this inlay hint.
+	 */
+	TextEdits []TextEdit `json:"textEdits,omitempty"`
+
+	/*Tooltip defined:
+	 * The tooltip text when you hover over this item.
+	 */
+	Tooltip *MarkupContent `json:"tooltip,omitempty"` // string | MarkupContent
+
+	/*PaddingLeft defined:
+	 * Render padding before the hint.
+	 */
+	PaddingLeft bool `json:"paddingLeft,omitempty"`
+
+	/*PaddingRight defined:
+	 * Render padding after the hint.
+	 */
+	PaddingRight bool `json:"paddingRight,omitempty"`
+
+	/*Data defined:
+	 * A data entry field that is preserved on an inlay hint between
+	 * a `textDocument/inlayHint` and a `inlayHint/resolve` request.
+	 */
+	Data interface{} `json:"data,omitempty"`
+}
+
+/*InlayHintLabelPart defined:
+ * An inlay hint label part allows for interactive and composite labels
+ * of inlay hints.
+ *
+ * @since 3.17.0
+ */
+type InlayHintLabelPart struct {
+
+	/*Value defined:
+	 * The value of this label part.
+	 */
+	Value string `json:"value"`
+
+	/*Tooltip defined:
+	 * The tooltip text when you hover over this label part.
+	 */
+	Tooltip *MarkupContent `json:"tooltip,omitempty"` // string | MarkupContent
+
+	/*Location defined:
+	 * An optional source code location that represents this label part.
+	 */
+	Location *Location `json:"location,omitempty"`
+
+	/*Command defined:
+	 * An optional command for this label part.
+	 */
+	Command *Command `json:"command,omitempty"`
+}
+
+/*InlayHintKind defined:
+ * Inlay hint kinds.
+ *
+ * @since 3.17.0
+ */
+type InlayHintKind float64
+
+const (
+
+	/*TypeInlayHint defined:
+	 * An inlay hint that for a type annotation.
+	 */
+	TypeInlayHint InlayHintKind = 1
+
+	/*ParameterInlayHint defined:
+	 * An inlay hint that is for a parameter.
+	 */
+	ParameterInlayHint InlayHintKind = 2
+)
+
+// InlayHintLabel is a type which represents the union of string and []InlayHintLabelPart.
+type InlayHintLabel []InlayHintLabelPart
+
+func (l *InlayHintLabel) UnmarshalJSON(data []byte) error {
+	d := strings.TrimSpace(string(data))
+	if len(d) == 0 || strings.EqualFold(d, "null") {
+		return nil
+	}
+	if d[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*l = InlayHintLabel{{Value: s}}
+		return nil
+	}
+	var parts []InlayHintLabelPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*l = parts
+	return nil
+}
+
+// String returns the label as a human readable string.
+func (l InlayHintLabel) String() string {
+	var b strings.Builder
+	for _, p := range l {
+		b.WriteString(p.Value)
+	}
+	return b.String()
+}
@@ -688,6 +688,13 @@ type TextDocumentClientCapabilities struct {
 	 */
 	SelectionRange *SelectionRangeClientCapabilities `json:"selectionRange,omitempty"`
 
+	/*InlayHint defined:
+	 * Capabilities specific to the `textDocument/inlayHint` request.
+	 *
+	 * @since 3.17.0
+	 */
+	InlayHint *InlayHintClientCapabilities `json:"inlayHint,omitempty"`
+
 	/*PublishDiagnostics defined:
 	 * Capabilities specific to `textDocument/publishDiagnostics`.
 	 */
@@ -1087,6 +1094,13 @@ type ServerCapabilities struct {
 	 */
 	SelectionRangeProvider bool `json:"selectionRangeProvider,omitempty"` // boolean | SelectionRangeOptions | SelectionRangeRegistrationOptions
 
+	/*InlayHintProvider defined:
+	 * The server provides inlay hints.
+	 *
+	 * @since 3.17.0
+	 */
+	InlayHintProvider interface{} `json:"inlayHintProvider,omitempty"` // boolean | InlayHintOptions | InlayHintRegistrationOptions
+
 	/*ExecuteCommandProvider defined:
 	 * The server provides execute command support.
 	 */
@@ -3307,7 +3321,7 @@ type CompletionItem struct {
 	/*Documentation defined:
 	 * A human-readable string that represents a doc-comment.
 	 */
-	Documentation MarkupContent `json:"documentation,omitempty"` // string | MarkupContent
+	Documentation *MarkupContent `json:"documentation,omitempty"` // string | MarkupContent
 
 	/*Deprecated defined:
 	 * Indicates if this item is deprecated.
@@ -3450,13 +3464,13 @@ type ParameterInformation struct {
 	 * *Note*: a label of type string should be a substring of its containing signature label.
 	 * Its intended use case is to highlight the parameter label part in the `SignatureInformation.label`.
 	 */
-	Label string `json:"label"` // string | [number, number]
+	Label ParameterLabel `json:"label"` // string | [number, number]
 
 	/*Documentation defined:
 	 * The human-readable doc-comment of this signature. Will be shown
 	 * in the UI but can be omitted.
 	 */
-	Documentation string `json:"documentation,omitempty"` // string | MarkupContent
+	Documentation *MarkupContent `json:"documentation,omitempty"` // string | MarkupContent
 }
 
 /*SignatureInformation defined:
@@ -3476,7 +3490,7 @@ type SignatureInformation struct {
 	 * The human-readable doc-comment of this signature. Will be shown
 	 * in the UI but can be omitted.
 	 */
-	Documentation string `json:"documentation,omitempty"` // string | MarkupContent
+	Documentation *MarkupContent `json:"documentation,omitempty"` // string | MarkupContent
 
 	/*Parameters defined:
 	 * The parameters of this signature.
@@ -32,6 +32,7 @@ type Server interface {
 	FoldingRange(context.Context, *FoldingRangeParams) ([]FoldingRange, error)
 	Declaration(context.Context, *DeclarationParams) ([]DeclarationLink, error)
 	SelectionRange(context.Context, *SelectionRangeParams) ([]SelectionRange, error)
+	InlayHint(context.Context, *InlayHintParams) ([]InlayHint, error)
 	Initialize(context.Context, *ParamInitia) (*InitializeResult, error)
 	Shutdown(context.Context) error
 	WillSaveWaitUntil(context.Context, *WillSaveTextDocumentParams) ([]TextEdit, error)
@@ -42,7 +43,7 @@ type Server interface {
 	Definition(context.Context, *DefinitionParams) ([]Location, error)
 	References(context.Context, *ReferenceParams) ([]Location, error)
 	DocumentHighlight(context.Context, *DocumentHighlightParams) ([]DocumentHighlight, error)
-	DocumentSymbol(context.Context, *DocumentSymbolParams) ([]DocumentSymbol, error)
+	DocumentSymbol(context.Context, *DocumentSymbolParams) (*DocumentSymbols, error)
 	CodeAction(context.Context, *CodeActionParams) ([]CodeAction, error)
 	Symbol(context.Context, *WorkspaceSymbolParams) ([]SymbolInformation, error)
 	CodeLens(context.Context, *CodeLensParams) ([]CodeLens, error)
@@ -269,6 +270,17 @@ func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, deliver
 			log.Error(ctx, "", err)
 		}
 		return true
+	case "textDocument/inlayHint": // req
+		var params InlayHintParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.InlayHint(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
 	case "initialize": // req
 		var params ParamInitia
 		if err := json.Unmarshal(*r.Params, &params); err != nil {
@@ -638,6 +650,14 @@ func (s *serverDispatcher) SelectionRange(ctx context.Context, params *Selection
 	return result, nil
 }
 
+func (s *serverDispatcher) InlayHint(ctx context.Context, params *InlayHintParams) ([]InlayHint, error) {
+	var result []InlayHint
+	if err := s.Conn.Call(ctx, "textDocument/inlayHint", params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (s *serverDispatcher) Initialize(ctx context.Context, params *ParamInitia) (*InitializeResult, error) {
 	var result InitializeResult
 	if err := s.Conn.Call(ctx, "initialize", params, &result); err != nil {
@@ -714,12 +734,12 @@ func (s *serverDispatcher) DocumentHighlight(ctx context.Context, params *Docume
 	return result, nil
 }
 
-func (s *serverDispatcher) DocumentSymbol(ctx context.Context, params *DocumentSymbolParams) ([]DocumentSymbol, error) {
-	var result []DocumentSymbol
+func (s *serverDispatcher) DocumentSymbol(ctx context.Context, params *DocumentSymbolParams) (*DocumentSymbols, error) {
+	var result DocumentSymbols
 	if err := s.Conn.Call(ctx, "textDocument/documentSymbol", params, &result); err != nil {
 		return nil, err
 	}
-	return result, nil
+	return &result, nil
 }
 
 func (s *serverDispatcher) CodeAction(ctx context.Context, params *CodeActionParams) ([]CodeAction, error) {
@@ -6,12 +6,15 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/telemetry/log"
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/telemetry/trace"
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/xcontext"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
 )
 
 type DocumentUri = string
@@ -78,3 +81,99 @@ type ExecuteCommandOnDocumentParams struct {
 	TextDocument         protocol.TextDocumentIdentifier
 	ExecuteCommandParams protocol.ExecuteCommandParams
 }
+
+type ResolveCodeLensOnDocumentParams struct {
+	TextDocument protocol.TextDocumentIdentifier
+	CodeLens     protocol.CodeLens
+}
+
+type ResolveDocumentLinkOnDocumentParams struct {
+	TextDocument protocol.TextDocumentIdentifier
+	DocumentLink protocol.DocumentLink
+}
+
+type ResolveCompletionItemOnDocumentParams struct {
+	TextDocument   protocol.TextDocumentIdentifier
+	CompletionItem protocol.CompletionItem
+}
+
+// TabStopsParams contains the tab stops of a snippet inserted into
+// an acme window. The tab stops are rune offsets within the window body.
+// The first tab stop is the one currently selected.
+type TabStopsParams struct {
+	WinID    int
+	TabStops []text.TabStop
+}
+
+// NextTabStopParams contains the current selection of an acme window.
+type NextTabStopParams struct {
+	WinID  int
+	Q0, Q1 int
+}
+
+// AddJumpParams contains the locations before and after a jump.
+type AddJumpParams struct {
+	From, To protocol.Location
+}
+
+// JumpParams contains the number of entries to move
+// through the navigation history.
+type JumpParams struct {
+	Delta int
+}
+
+// JumpsResult contains the navigation history.
+type JumpsResult struct {
+	Entries []protocol.Location
+	Current int // index of the current entry
+}
+
+// ShowResultsParams contains the L command (e.g. "refs") whose
+// output is shown in a window, and where the command is run.
+type ShowResultsParams struct {
+	Command  string
+	WinID    int
+	Position protocol.TextDocumentPositionParams
+}
+
+// StatusResult contains the status of the LSP servers.
+type StatusResult struct {
+	Workspaces []protocol.WorkspaceFolder // shared by all servers
+	Servers    []ServerStatus
+}
+
+// ServerStatus contains the status of a LSP server,
+// which handles files matching Pattern.
+type ServerStatus struct {
+	Pattern      string
+	Command      []string // empty if the server is dialed
+	Address      string   // dial address, if the server is dialed
+	Running      bool     // false if the server hasn't been started yet
+	PID          int      // 0 if the server is dialed
+	Started      time.Time
+	Restarts     int
+	ExitError    string           // error returned by the last server process
+	Capabilities []string         // e.g. "hover", "definition"
+	Documents    []DocumentStatus // documents open in the server
+}
+
+// DocumentStatus contains a document open in a LSP server.
+type DocumentStatus struct {
+	URI     protocol.DocumentURI
+	Version float64
+}
+
+// ServerParams identifies LSP servers by the key of their
+// configuration (e.g. "gopls") or the name of a file they handle.
+type ServerParams struct {
+	Name string
+}
+
+// RawParams contains an arbitrary LSP request or notification
+// and the document whose LSP server it's sent to.
+type RawParams struct {
+	TextDocument protocol.TextDocumentIdentifier
+	Method       string
+	Params       json.RawMessage
+	Notification bool // send a notification instead of a request
+}
@@ -8,10 +8,11 @@ import (
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/telemetry/log"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+	"github.com/tw4452852/acme-lsp/internal/lsp/text"
 )
 
 // Version is used to detect if acme-lsp and L are speaking the same protocol.
-const Version = 1
+const Version = 13
 
 // Server implements a subset of an LSP protocol server as defined by protocol.Server and
 // some custom acme-lsp specific methods.
@@ -35,18 +36,85 @@ type Server interface {
 	// ExecuteCommand request to the right server.
 	ExecuteCommandOnDocument(context.Context, *ExecuteCommandOnDocumentParams) (interface{}, error)
 
+	// ResolveCodeLensOnDocument is the same as ResolveCodeLens, but
+	// params contain the TextDocumentIdentifier of the document
+	// containing the code lens so that the server implemention
+	// can multiplex ResolveCodeLens request to the right server.
+	ResolveCodeLensOnDocument(context.Context, *ResolveCodeLensOnDocumentParams) (*protocol.CodeLens, error)
+
+	// ResolveDocumentLinkOnDocument is the same as ResolveDocumentLink,
+	// but params contain the TextDocumentIdentifier of the document
+	// containing the link so that the server implemention can
+	// multiplex ResolveDocumentLink request to the right server.
+	ResolveDocumentLinkOnDocument(context.Context, *ResolveDocumentLinkOnDocumentParams) (*protocol.DocumentLink, error)
+
+	// ResolveCompletionItemOnDocument is the same as Resolve
+	// (completionItem/resolve), but params contain the
+	// TextDocumentIdentifier of the document being completed so
+	// that the server implemention can multiplex the request to
+	// the right server.
+	ResolveCompletionItemOnDocument(context.Context, *ResolveCompletionItemOnDocumentParams) (*protocol.CompletionItem, error)
+
+	// SetTabStops remembers the tab stops of a snippet inserted into
+	// an acme window, so that they can be visited using NextTabStop.
+	SetTabStops(context.Context, *TabStopsParams) error
+
+	// NextTabStop returns the tab stop following the currently selected
+	// one in an acme window. The tab stop is adjusted for the text
+	// typed over the current tab stop.
+	NextTabStop(context.Context, *NextTabStopParams) (*text.TabStop, error)
+
+	// AddJump records a jump between two locations in the navigation
+	// history, discarding the history forward of the current entry.
+	AddJump(context.Context, *AddJumpParams) error
+
+	// Jump moves through the navigation history by the number of entries
+	// given in params (negative moves back) and returns the location of
+	// the new current entry.
+	Jump(context.Context, *JumpParams) (*protocol.Location, error)
+
+	// Jumps returns the navigation history.
+	Jumps(context.Context) (*JumpsResult, error)
+
+	// ShowResults runs an L command at the given position and writes its
+	// output to an acme window named after the command (e.g. /LSP/refs).
+	// The window is reused by later calls, and executing Reload in its
+	// tag runs the command again.
+	ShowResults(context.Context, *ShowResultsParams) error
+
+	// Status returns the status of the LSP servers.
+	Status(context.Context) (*StatusResult, error)
+
+	// RestartServer shuts down the LSP servers given in params, starts
+	// them again, and reopens the documents that were open in them.
+	RestartServer(context.Context, *ServerParams) error
+
+	// StopServer shuts down the LSP servers given in params. A stopped
+	// server is started again when it's needed, and the documents that
+	// were open in it are reopened.
+	StopServer(context.Context, *ServerParams) error
+
+	// Raw sends an arbitrary request or notification to the LSP server
+	// for the given document and returns the result as is.
+	Raw(context.Context, *RawParams) (json.RawMessage, error)
+
+	DidOpen(context.Context, *protocol.DidOpenTextDocumentParams) error
 	DidChange(context.Context, *protocol.DidChangeTextDocumentParams) error
+	DidClose(context.Context, *protocol.DidCloseTextDocumentParams) error
 	DidChangeWorkspaceFolders(context.Context, *protocol.DidChangeWorkspaceFoldersParams) error
 	Completion(context.Context, *protocol.CompletionParams) (*protocol.CompletionList, error)
 	Definition(context.Context, *protocol.DefinitionParams) ([]protocol.Location, error)
 	Formatting(context.Context, *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error)
 	CodeAction(context.Context, *protocol.CodeActionParams) ([]protocol.CodeAction, error)
+	CodeLens(context.Context, *protocol.CodeLensParams) ([]protocol.CodeLens, error)
+	DocumentLink(context.Context, *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error)
 	Hover(context.Context, *protocol.HoverParams) (*protocol.Hover, error)
 	Implementation(context.Context, *protocol.ImplementationParams) ([]protocol.Location, error)
+	InlayHint(context.Context, *protocol.InlayHintParams) ([]protocol.InlayHint, error)
 	References(context.Context, *protocol.ReferenceParams) ([]protocol.Location, error)
 	Rename(context.Context, *protocol.RenameParams) (*protocol.WorkspaceEdit, error)
 	SignatureHelp(context.Context, *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error)
-	DocumentSymbol(context.Context, *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error)
+	DocumentSymbol(context.Context, *protocol.DocumentSymbolParams) (*protocol.DocumentSymbols, error)
 	TypeDefinition(context.Context, *protocol.TypeDefinitionParams) ([]protocol.Location, error)
 }
 
@@ -102,6 +170,152 @@ func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, deliver
 		}
 		return true
 
+	case "acme-lsp/resolveCodeLensOnDocument": // req
+		var params ResolveCodeLensOnDocumentParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.ResolveCodeLensOnDocument(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/resolveDocumentLinkOnDocument": // req
+		var params ResolveDocumentLinkOnDocumentParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.ResolveDocumentLinkOnDocument(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/resolveCompletionItemOnDocument": // req
+		var params ResolveCompletionItemOnDocumentParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.ResolveCompletionItemOnDocument(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/setTabStops": // req
+		var params TabStopsParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		err := h.server.SetTabStops(ctx, &params)
+		if err := r.Reply(ctx, nil, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/nextTabStop": // req
+		var params NextTabStopParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.NextTabStop(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/addJump": // req
+		var params AddJumpParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		err := h.server.AddJump(ctx, &params)
+		if err := r.Reply(ctx, nil, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/jump": // req
+		var params JumpParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.Jump(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/jumps": // req
+		resp, err := h.server.Jumps(ctx)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/showResults": // req
+		var params ShowResultsParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		err := h.server.ShowResults(ctx, &params)
+		if err := r.Reply(ctx, nil, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/status": // req
+		resp, err := h.server.Status(ctx)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/restartServer": // req
+		var params ServerParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		err := h.server.RestartServer(ctx, &params)
+		if err := r.Reply(ctx, nil, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/stopServer": // req
+		var params ServerParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		err := h.server.StopServer(ctx, &params)
+		if err := r.Reply(ctx, nil, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
+	case "acme-lsp/raw": // req
+		var params RawParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.Raw(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+
 	default:
 		return false
 	}
@@ -144,6 +358,90 @@ func (s *serverDispatcher) ExecuteCommandOnDocument(ctx context.Context, params
 	return result, nil
 }
 
+func (s *serverDispatcher) ResolveCodeLensOnDocument(ctx context.Context, params *ResolveCodeLensOnDocumentParams) (*protocol.CodeLens, error) {
+	var result protocol.CodeLens
+	if err := s.Conn.Call(ctx, "acme-lsp/resolveCodeLensOnDocument", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) ResolveDocumentLinkOnDocument(ctx context.Context, params *ResolveDocumentLinkOnDocumentParams) (*protocol.DocumentLink, error) {
+	var result protocol.DocumentLink
+	if err := s.Conn.Call(ctx, "acme-lsp/resolveDocumentLinkOnDocument", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) ResolveCompletionItemOnDocument(ctx context.Context, params *ResolveCompletionItemOnDocumentParams) (*protocol.CompletionItem, error) {
+	var result protocol.CompletionItem
+	if err := s.Conn.Call(ctx, "acme-lsp/resolveCompletionItemOnDocument", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) SetTabStops(ctx context.Context, params *TabStopsParams) error {
+	return s.Conn.Call(ctx, "acme-lsp/setTabStops", params, nil)
+}
+
+func (s *serverDispatcher) NextTabStop(ctx context.Context, params *NextTabStopParams) (*text.TabStop, error) {
+	var result text.TabStop
+	if err := s.Conn.Call(ctx, "acme-lsp/nextTabStop", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) AddJump(ctx context.Context, params *AddJumpParams) error {
+	return s.Conn.Call(ctx, "acme-lsp/addJump", params, nil)
+}
+
+func (s *serverDispatcher) Jump(ctx context.Context, params *JumpParams) (*protocol.Location, error) {
+	var result protocol.Location
+	if err := s.Conn.Call(ctx, "acme-lsp/jump", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) Jumps(ctx context.Context) (*JumpsResult, error) {
+	var result JumpsResult
+	if err := s.Conn.Call(ctx, "acme-lsp/jumps", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) ShowResults(ctx context.Context, params *ShowResultsParams) error {
+	return s.Conn.Call(ctx, "acme-lsp/showResults", params, nil)
+}
+
+func (s *serverDispatcher) Status(ctx context.Context) (*StatusResult, error) {
+	var result StatusResult
+	if err := s.Conn.Call(ctx, "acme-lsp/status", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) RestartServer(ctx context.Context, params *ServerParams) error {
+	return s.Conn.Call(ctx, "acme-lsp/restartServer", params, nil)
+}
+
+func (s *serverDispatcher) StopServer(ctx context.Context, params *ServerParams) error {
+	return s.Conn.Call(ctx, "acme-lsp/stopServer", params, nil)
+}
+
+func (s *serverDispatcher) Raw(ctx context.Context, params *RawParams) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := s.Conn.Call(ctx, "acme-lsp/raw", params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 type CancelParams struct {
 	/**
 	 * The request id to cancel.
@@ -172,14 +470,6 @@ func (s *lspServerDispatcher) DidChangeConfiguration(context.Context, *protocol.
 	return fmt.Errorf("not implemented")
 }
 
-func (s *lspServerDispatcher) DidOpen(context.Context, *protocol.DidOpenTextDocumentParams) error {
-	return fmt.Errorf("not implemented")
-}
-
-func (s *lspServerDispatcher) DidClose(context.Context, *protocol.DidCloseTextDocumentParams) error {
-	return fmt.Errorf("not implemented")
-}
-
 func (s *lspServerDispatcher) DidSave(context.Context, *protocol.DidSaveTextDocumentParams) error {
 	return fmt.Errorf("not implemented")
 }
@@ -248,10 +538,6 @@ func (s *lspServerDispatcher) Symbol(context.Context, *protocol.WorkspaceSymbolP
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (s *lspServerDispatcher) CodeLens(context.Context, *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-
 func (s *lspServerDispatcher) ResolveCodeLens(context.Context, *protocol.CodeLens) (*protocol.CodeLens, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -268,10 +554,6 @@ func (s *lspServerDispatcher) PrepareRename(context.Context, *protocol.PrepareRe
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (s *lspServerDispatcher) DocumentLink(context.Context, *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
-	return nil, fmt.Errorf("not implemented")
-}
-
 func (s *lspServerDispatcher) ResolveDocumentLink(context.Context, *protocol.DocumentLink) (*protocol.DocumentLink, error) {
 	return nil, fmt.Errorf("not implemented")
 }
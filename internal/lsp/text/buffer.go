@@ -0,0 +1,84 @@
+package text
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Buffer is an AddressableFile holding the text of a file in memory.
+// It's used to work on a file without acme.
+type Buffer struct {
+	name   string
+	body   []rune
+	q0, q1 int // current address
+}
+
+// NewBuffer returns a Buffer for the file with the given
+// name and text. The current address is at the beginning.
+func NewBuffer(filename string, body []byte) *Buffer {
+	return &Buffer{
+		name: filename,
+		body: []rune(string(body)),
+	}
+}
+
+// Filename returns the filesystem path to the file.
+func (b *Buffer) Filename() (string, error) {
+	return b.name, nil
+}
+
+// CurrentAddr returns the current address.
+func (b *Buffer) CurrentAddr() (q0, q1 int, err error) {
+	return b.q0, b.q1, nil
+}
+
+// SetPosition sets the current address to the position
+// at zero-based line and rune offset col within the line.
+func (b *Buffer) SetPosition(line, col int) error {
+	off, err := getNewlineOffsets(strings.NewReader(string(b.body)))
+	if err != nil {
+		return err
+	}
+	if line >= len(off.nl) {
+		return fmt.Errorf("%v: line %v is beyond end of file", b.name, line+1)
+	}
+	n := off.leftover
+	if line+1 < len(off.nl) {
+		n = off.nl[line+1] - off.nl[line] - 1 // excluding '\n'
+	}
+	if col > n {
+		return fmt.Errorf("%v: column %v is beyond end of line %v", b.name, col+1, line+1)
+	}
+	b.q0 = off.LineToOffset(line, col)
+	b.q1 = b.q0
+	return nil
+}
+
+// Reader returns a reader for the text.
+func (b *Buffer) Reader() (io.Reader, error) {
+	return strings.NewReader(string(b.body)), nil
+}
+
+// WriteAt replaces the text in rune range [q0, q1) with bytes p.
+func (b *Buffer) WriteAt(q0, q1 int, p []byte) (int, error) {
+	if q0 < 0 || q0 > q1 || q1 > len(b.body) {
+		return 0, fmt.Errorf("invalid range [%v, %v)", q0, q1)
+	}
+	body := make([]rune, 0, len(b.body))
+	body = append(body, b.body[:q0]...)
+	body = append(body, []rune(string(p))...)
+	b.body = append(body, b.body[q1:]...)
+	return len(p), nil
+}
+
+// Mark does nothing since Buffer doesn't support undo.
+func (b *Buffer) Mark() error { return nil }
+
+// DisableMark does nothing since Buffer doesn't support undo.
+func (b *Buffer) DisableMark() error { return nil }
+
+// Bytes returns the text.
+func (b *Buffer) Bytes() []byte {
+	return []byte(string(b.body))
+}
@@ -0,0 +1,54 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
+)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer("/home/gopher/main.go", []byte("package main\n\nfunc main() {\n\tfmt.Println(\"héllo\")\n}\n"))
+	if err := b.SetPosition(3, 5); err != nil {
+		t.Fatalf("SetPosition failed: %v", err)
+	}
+	pos, _, err := Position(b)
+	if err != nil {
+		t.Fatalf("Position failed: %v", err)
+	}
+	if want := (protocol.Position{Line: 3, Character: 5}); pos.Position != want {
+		t.Errorf("position is %v; want %v", pos.Position, want)
+	}
+	if err := b.SetPosition(10, 0); err == nil {
+		t.Errorf("SetPosition beyond end of file succeeded")
+	}
+	if err := b.SetPosition(2, 14); err == nil {
+		t.Errorf("SetPosition beyond end of line succeeded")
+	}
+	if err := b.SetPosition(2, 13); err != nil {
+		t.Errorf("SetPosition at end of line failed: %v", err)
+	}
+
+	err = Edit(b, []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 3, Character: 14},
+				End:   protocol.Position{Line: 3, Character: 19},
+			},
+			NewText: "world",
+		},
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 0},
+				End:   protocol.Position{Line: 1, Character: 0},
+			},
+			NewText: "import \"fmt\"\n",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	want := "package main\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"world\")\n}\n"
+	if got := string(b.Bytes()); got != want {
+		t.Errorf("text after edit is %q; want %q", got, want)
+	}
+}
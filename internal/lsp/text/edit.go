@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"unicode/utf8"
 
 	"github.com/tw4452852/acme-lsp/internal/golang_org_x_tools/span"
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
@@ -55,7 +56,7 @@ func Edit(f File, edits []protocol.TextEdit) error {
 
 	// Make sure edits follow the sequence from beginning to end
 	// See comments below.
-	sort.Sort(EditList(edits))
+	sort.Stable(EditList(edits))
 
 	// Applying the edits in reverse order gets the job done.
 	// See https://github.com/golang/go/wiki/gopls#textdocumentformatting-response
@@ -68,6 +69,35 @@ func Edit(f File, edits []protocol.TextEdit) error {
 	return nil
 }
 
+// EditOffset applies edits to file f, like Edit, and returns the rune
+// offset within the edited file where the new text of edits[i] begins.
+func EditOffset(f File, edits []protocol.TextEdit, i int) (int, error) {
+	reader, err := f.Reader()
+	if err != nil {
+		return 0, err
+	}
+	off, err := getNewlineOffsets(reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain newline offsets: %v", err)
+	}
+	pos := edits[i].Range.Start
+	q := off.LineToOffset(int(pos.Line), int(pos.Character))
+
+	// Adjust the offset for the edits that come before edits[i].
+	offset := q
+	for j, e := range edits {
+		q0 := off.LineToOffset(int(e.Range.Start.Line), int(e.Range.Start.Character))
+		q1 := off.LineToOffset(int(e.Range.End.Line), int(e.Range.End.Character))
+		if j != i && q0 < q {
+			offset += utf8.RuneCountInString(e.NewText) - (q1 - q0)
+		}
+	}
+	if err := Edit(f, edits); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
 // AddressableFile represents an open file in text editor which has a current adddress.
 type AddressableFile interface {
 	File
@@ -118,6 +148,34 @@ func Position(f AddressableFile) (pos *protocol.TextDocumentPositionParams, file
 	}, name, nil
 }
 
+// Selection returns the range of the current selection within a file being edited.
+func Selection(f AddressableFile) (*protocol.Range, error) {
+	q0, q1, err := f.CurrentAddr()
+	if err != nil {
+		return nil, fmt.Errorf("could not get current address: %v", err)
+	}
+	reader, err := f.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("could not get window body reader: %v", err)
+	}
+	off, err := getNewlineOffsets(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get newline offset: %v", err)
+	}
+	line0, col0 := off.OffsetToLine(q0)
+	line1, col1 := off.OffsetToLine(q1)
+	return &protocol.Range{
+		Start: protocol.Position{
+			Line:      float64(line0),
+			Character: float64(col0),
+		},
+		End: protocol.Position{
+			Line:      float64(line1),
+			Character: float64(col1),
+		},
+	}, nil
+}
+
 // ToURI converts filename to URI.
 func ToURI(filename string) protocol.DocumentURI {
 	return protocol.DocumentURI(span.NewURI(filename))
@@ -1,7 +1,10 @@
 package text
 
 import (
+	"fmt"
+	"io"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/tw4452852/acme-lsp/internal/lsp/protocol"
@@ -28,3 +31,81 @@ func TestURI(t *testing.T) {
 		}
 	}
 }
+
+// runeFile implements File using an in-memory buffer.
+type runeFile struct {
+	body []rune
+}
+
+func (f *runeFile) Reader() (io.Reader, error) {
+	return strings.NewReader(string(f.body)), nil
+}
+
+func (f *runeFile) WriteAt(q0, q1 int, b []byte) (int, error) {
+	f.body = append(f.body[:q0], append([]rune(string(b)), f.body[q1:]...)...)
+	return len(b), nil
+}
+
+func (f *runeFile) Mark() error        { return nil }
+func (f *runeFile) DisableMark() error { return nil }
+
+func TestEditOffset(t *testing.T) {
+	f := &runeFile{body: []rune("package main\n\nfunc main() {\n\tfmt.P\n}\n")}
+	edits := []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 0},
+				End:   protocol.Position{Line: 1, Character: 0},
+			},
+			NewText: "import \"fmt\"\n\n",
+		},
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 3, Character: 5},
+				End:   protocol.Position{Line: 3, Character: 6},
+			},
+			NewText: "Println",
+		},
+	}
+	got, err := EditOffset(f, edits, 1)
+	if err != nil {
+		t.Fatalf("EditOffset failed: %v", err)
+	}
+	wantBody := "package main\nimport \"fmt\"\n\n\nfunc main() {\n\tfmt.Println\n}\n"
+	if string(f.body) != wantBody {
+		t.Errorf("edited body is %q; want %q", string(f.body), wantBody)
+	}
+	if want := strings.Index(wantBody, "Println"); got != want {
+		t.Errorf("offset is %v; want %v", got, want)
+	}
+}
+
+func TestEditSamePosition(t *testing.T) {
+	f := &runeFile{body: []rune("x\ny\n")}
+	// Enough edits to not be sorted by insertion sort,
+	// which happens to be stable.
+	var edits []protocol.TextEdit
+	var want strings.Builder
+	for i := 0; i < 20; i++ {
+		line := float64(i % 2)
+		edits = append(edits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line},
+				End:   protocol.Position{Line: line},
+			},
+			NewText: fmt.Sprint(i),
+		})
+	}
+	for _, line := range []int{0, 1} {
+		for i := line; i < 20; i += 2 {
+			fmt.Fprint(&want, i)
+		}
+		want.WriteString([]string{"x\n", "y\n"}[line])
+	}
+	if err := Edit(f, edits); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if got := string(f.body); got != want.String() {
+		t.Errorf("edited body is %q; want %q", got, want.String())
+	}
+}
@@ -0,0 +1,248 @@
+package text
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Snippet is an LSP snippet converted to plain text.
+// See https://microsoft.github.io/language-server-protocol/specifications/specification-current/#snippet_syntax
+type Snippet struct {
+	// Text is the snippet text with the snippet syntax removed.
+	// Placeholders are replaced by their default text, choices by
+	// their first option, and variables by their default text, if any.
+	Text string
+
+	// TabStops are the tab stops within Text, in the order
+	// they should be visited ($1, $2, ..., and finally $0).
+	TabStops []TabStop
+}
+
+// TabStop represents the rune range [Q0, Q1) within a text.
+type TabStop struct {
+	Q0, Q1 int
+}
+
+// ParseSnippet converts snippet s to plain text and tab stops.
+// Malformed snippet syntax is kept as literal text.
+func ParseSnippet(s string) *Snippet {
+	p := &snippetParser{
+		in:    []rune(s),
+		stops: make(map[int]TabStop),
+		text:  make(map[int]string),
+	}
+	p.parse(false)
+
+	var nums []int
+	for n := range p.stops {
+		if n != 0 {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	var stops []TabStop
+	for _, n := range nums {
+		stops = append(stops, p.stops[n])
+	}
+	if ts, ok := p.stops[0]; ok {
+		stops = append(stops, ts)
+	} else if len(stops) > 0 {
+		// Implicit final tab stop at the end of the snippet.
+		stops = append(stops, TabStop{len(p.out), len(p.out)})
+	}
+	return &Snippet{
+		Text:     string(p.out),
+		TabStops: stops,
+	}
+}
+
+type snippetParser struct {
+	in    []rune
+	pos   int
+	out   []rune
+	stops map[int]TabStop // first occurrence of each tab stop
+	text  map[int]string  // placeholder text of each tab stop
+	added []int           // tab stops in the order they were added
+}
+
+func (p *snippetParser) peek() rune {
+	if p.pos < len(p.in) {
+		return p.in[p.pos]
+	}
+	return 0
+}
+
+// parse parses text until the end of input or, if nested is true,
+// until the unescaped '}' which ends a placeholder.
+func (p *snippetParser) parse(nested bool) bool {
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.in) && strings.ContainsRune(`$}\`, p.in[p.pos+1]):
+			p.out = append(p.out, p.in[p.pos+1])
+			p.pos += 2
+		case c == '$':
+			if !p.parseDollar() {
+				p.out = append(p.out, c)
+				p.pos++
+			}
+		case c == '}' && nested:
+			p.pos++
+			return true
+		default:
+			p.out = append(p.out, c)
+			p.pos++
+		}
+	}
+	return false
+}
+
+// parseDollar parses a tab stop, placeholder, choice or variable.
+// It returns false, without consuming any input, if the input
+// is not valid snippet syntax.
+func (p *snippetParser) parseDollar() bool {
+	pos, nout, nadded := p.pos, len(p.out), len(p.added)
+	ok := p.parseDollar1()
+	if !ok {
+		// Forget the tab stops of nested placeholders too.
+		for _, n := range p.added[nadded:] {
+			delete(p.stops, n)
+			delete(p.text, n)
+		}
+		p.pos, p.out, p.added = pos, p.out[:nout], p.added[:nadded]
+	}
+	return ok
+}
+
+func (p *snippetParser) parseDollar1() bool {
+	p.pos++ // '$'
+
+	// $1
+	if n, ok := p.parseInt(); ok {
+		p.addTabStop(n, len(p.out))
+		return true
+	}
+	// $VAR
+	if name := p.parseName(); name != "" {
+		return true
+	}
+	if p.peek() != '{' {
+		return false
+	}
+	p.pos++
+	if n, ok := p.parseInt(); ok {
+		start := len(p.out)
+		switch p.peek() {
+		case '}': // ${1}
+			p.pos++
+			p.addTabStop(n, start)
+			return true
+		case ':': // ${1:placeholder}
+			p.pos++
+			if !p.parse(true) {
+				return false
+			}
+			p.addTabStop(n, start)
+			return true
+		case '|': // ${1|one,two|}
+			p.pos++
+			choices, ok := p.parseChoices()
+			if !ok {
+				return false
+			}
+			p.out = append(p.out, []rune(choices[0])...)
+			p.addTabStop(n, start)
+			return true
+		}
+		return false
+	}
+	if name := p.parseName(); name != "" {
+		switch p.peek() {
+		case '}': // ${VAR}
+			p.pos++
+			return true
+		case ':': // ${VAR:default}
+			p.pos++
+			return p.parse(true)
+		case '/': // ${VAR/regex/format/options}
+			for p.pos < len(p.in) {
+				switch p.in[p.pos] {
+				case '\\':
+					p.pos += 2
+				case '}':
+					p.pos++
+					return true
+				default:
+					p.pos++
+				}
+			}
+		}
+	}
+	return false
+}
+
+// addTabStop adds tab stop n, which starts at start and ends
+// at the current output position. If the tab stop has already been
+// seen, this one mirrors its text.
+func (p *snippetParser) addTabStop(n, start int) {
+	if _, ok := p.stops[n]; ok {
+		if start == len(p.out) {
+			p.out = append(p.out, []rune(p.text[n])...)
+		}
+		return
+	}
+	p.stops[n] = TabStop{start, len(p.out)}
+	p.text[n] = string(p.out[start:])
+	p.added = append(p.added, n)
+}
+
+func (p *snippetParser) parseInt() (int, bool) {
+	n, ok := 0, false
+	for p.pos < len(p.in) && '0' <= p.in[p.pos] && p.in[p.pos] <= '9' {
+		n = n*10 + int(p.in[p.pos]-'0')
+		p.pos++
+		ok = true
+	}
+	return n, ok
+}
+
+func (p *snippetParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		if c == '_' || unicode.IsLetter(c) || (p.pos > start && unicode.IsDigit(c)) {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return string(p.in[start:p.pos])
+}
+
+// parseChoices parses the options of a choice until the closing "|}".
+func (p *snippetParser) parseChoices() ([]string, bool) {
+	var (
+		choices []string
+		b       strings.Builder
+	)
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.in) && strings.ContainsRune(`$}\,|`, p.in[p.pos+1]):
+			b.WriteRune(p.in[p.pos+1])
+			p.pos += 2
+		case c == ',':
+			choices = append(choices, b.String())
+			b.Reset()
+			p.pos++
+		case c == '|' && p.pos+1 < len(p.in) && p.in[p.pos+1] == '}':
+			p.pos += 2
+			return append(choices, b.String()), true
+		default:
+			b.WriteRune(c)
+			p.pos++
+		}
+	}
+	return nil, false
+}
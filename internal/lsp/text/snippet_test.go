@@ -0,0 +1,92 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    *Snippet
+	}{
+		{"PlainText", "fmt", &Snippet{Text: "fmt"}},
+		{
+			"Placeholders",
+			"Printf(${1:format string}, ${2:a ...interface{\\}})$0",
+			&Snippet{
+				Text:     "Printf(format string, a ...interface{})",
+				TabStops: []TabStop{{7, 20}, {22, 38}, {39, 39}},
+			},
+		},
+		{
+			"ImplicitFinalTabStop",
+			"append(${1:slice}, $2)",
+			&Snippet{
+				Text:     "append(slice, )",
+				TabStops: []TabStop{{7, 12}, {14, 14}, {15, 15}},
+			},
+		},
+		{
+			"OutOfOrder",
+			"$0 ${2:b} ${1:a}",
+			&Snippet{
+				Text:     " b a",
+				TabStops: []TabStop{{3, 4}, {1, 2}, {0, 0}},
+			},
+		},
+		{
+			"MalformedNested",
+			"${1:a ${2:b}",
+			&Snippet{
+				Text:     "${1:a b",
+				TabStops: []TabStop{{6, 7}, {7, 7}},
+			},
+		},
+		{
+			"Nested",
+			"${1:foo ${2:bar}}",
+			&Snippet{
+				Text:     "foo bar",
+				TabStops: []TabStop{{0, 7}, {4, 7}, {7, 7}},
+			},
+		},
+		{
+			"Choice",
+			"${1|one,two\\,three|}",
+			&Snippet{
+				Text:     "one",
+				TabStops: []TabStop{{0, 3}, {3, 3}},
+			},
+		},
+		{
+			"Mirror",
+			"${1:x} = $1",
+			&Snippet{
+				Text:     "x = x",
+				TabStops: []TabStop{{0, 1}, {5, 5}},
+			},
+		},
+		{
+			"Variables",
+			"$TM_FILENAME ${TM_SELECTED_TEXT:sel} ${TM_LINE_NUMBER/(.*)/$1/}",
+			&Snippet{Text: " sel "},
+		},
+		{"Escapes", `\$1 \\ \}`, &Snippet{Text: `$1 \ }`}},
+		{"Malformed", "${1:oops $ ${", &Snippet{Text: "${1:oops $ ${"}},
+		{"Unicode", "f(${1:héllo})", &Snippet{
+			Text:     "f(héllo)",
+			TabStops: []TabStop{{2, 7}, {8, 8}},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseSnippet(tc.snippet)
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("ParseSnippet(%q) is %+v; want %+v", tc.snippet, got, tc.want)
+			}
+		})
+	}
+}